@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/blysin/autocmdr/pkg/audit"
+)
+
+// dateLayouts are the accepted formats for -since and -until
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"}
+
+// runAuditCommand handles "autocmdr audit", printing audit entries that match the filters
+func (a *App) runAuditCommand(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	since := fs.String("since", "", "Show entries at or after this date (YYYY-MM-DD or RFC 3339)")
+	until := fs.String("until", "", "Show entries up to this date; a bare date includes the whole day")
+	exitCode := fs.Int("exit-code", 0, "Show executions with this exit code")
+	command := fs.String("command", "", "Show entries whose command contains this text")
+	asJSON := fs.Bool("json", false, "Print matching entries as JSON Lines")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var filter audit.Filter
+	var err error
+	if filter.Since, err = parseDate(*since, false); err != nil {
+		return err
+	}
+	if filter.Until, err = parseDate(*until, true); err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "exit-code" {
+			filter.ExitCode = exitCode
+		}
+	})
+	filter.Command = *command
+
+	entries, err := audit.NewLog(a.cfg.ConfigDir).Query(filter)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No audit entries found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tSESSION\tAPPROVAL\tEXIT\tCOMMAND")
+	for _, entry := range entries {
+		exit := "-"
+		if entry.Execution != nil {
+			exit = fmt.Sprintf("%d", entry.Execution.ExitCode)
+		}
+		session := entry.SessionID
+		if session == "" {
+			session = "-"
+		}
+		approval := string(entry.Approval)
+		if approval == "" {
+			approval = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"), session, approval, exit,
+			strings.Join(strings.Fields(audit.Command(entry)), " "))
+	}
+	return w.Flush()
+}
+
+// parseDate parses a -since or -until value in local time.
+// With endOfDay, a bare date is moved to the start of the following day so that the whole day is included.
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			continue
+		}
+		if endOfDay && layout == "2006-01-02" {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD, YYYY-MM-DD HH:MM or RFC 3339", value)
+}
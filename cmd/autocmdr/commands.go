@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a subcommand of the autocmdr binary, such as "autocmdr sessions list"
+type command struct {
+	name  string
+	usage string
+	run   func(a *App, args []string) error
+}
+
+// commands lists the available subcommands
+var commands = []command{
+	{name: "sessions", usage: "sessions list | sessions delete <id>", run: (*App).runSessionsCommand},
+	{name: "explain", usage: "explain [-json] <command>", run: (*App).runExplainCommand},
+	{name: "audit", usage: "audit [-since date] [-until date] [-exit-code n] [-command text] [-json]", run: (*App).runAuditCommand},
+	{name: "eval", usage: "eval [-run pattern] [-no-exec] [-min-pass-rate n] [-v] [-json] <suite.yaml>...", run: (*App).runEvalCommand},
+}
+
+// findCommand returns the subcommand with the given name, or nil
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// runCommand runs a subcommand and exits with a non-zero code on failure
+func (a *App) runCommand(cmd *command, args []string) {
+	if err := cmd.run(a, args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Usage: autocmdr %s\n", cmd.usage)
+		os.Exit(exitError)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/blysin/autocmdr/pkg/chat"
+	"github.com/blysin/autocmdr/pkg/eval"
+)
+
+// evalTimeout stops scripts run by eval when no exec_timeout is configured
+const evalTimeout = 30 * time.Second
+
+// runEvalCommand handles "autocmdr eval <suite.yaml>...", running the tasks of the suites against
+// the configured model and printing the pass rate
+func (a *App) runEvalCommand(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	run := fs.String("run", "", "Only run tasks whose names match this regular expression")
+	noExec := fs.Bool("no-exec", false, "Check the scripts without executing them")
+	minPassRate := fs.Float64("min-pass-rate", 0, "Fail when the share of passed tasks, from 0 to 1, is lower")
+	verbose := fs.Bool("v", false, "Show the scripts of passed tasks too")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("missing suite file")
+	}
+
+	var suites []*eval.Suite
+	for _, path := range fs.Args() {
+		suite, err := eval.LoadSuite(path)
+		if err != nil {
+			return err
+		}
+		suites = append(suites, suite)
+	}
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			return fmt.Errorf("invalid -run pattern: %w", err)
+		}
+	}
+	if err := a.cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if a.logger.GetLevel() == logrus.InfoLevel {
+		a.logger.SetLevel(logrus.WarnLevel)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.setupShutdownHandler(cancel, syscall.SIGINT, syscall.SIGTERM)
+
+	llm := a.initLLM()
+	// A response that cannot be parsed fails its task instead of being repaired
+	options := a.chatOptions()
+	options.MaxRepairAttempts = 0
+	executor, err := a.evalExecutor("")
+	if err != nil {
+		return err
+	}
+	// The environment is left out of the prompt, so that results do not depend on where eval runs
+	assistant := chat.NewCliAssistant(options, executor, a.logger)
+	a.initTemplate(assistant)
+
+	var newExecutor eval.ExecutorFactory
+	if !*noExec {
+		newExecutor = a.evalExecutor
+	}
+	runner := eval.NewRunner(func(ctx context.Context, request string) (*chat.AssistantResult, error) {
+		return assistant.RunOnce(ctx, llm, request)
+	}, newExecutor)
+	runner.SetPolicy(a.initPolicy())
+
+	report := &eval.Report{Model: a.cfg.Provider + "/" + a.cfg.Model}
+	for _, suite := range suites {
+		fmt.Fprintf(os.Stderr, "Running suite %s (%d tasks)\n", suite.Name, len(suite.Tasks))
+		report.Results = append(report.Results, runner.Run(ctx, suite, filter)...)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("evaluation interrupted")
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			*eval.Report
+			Passed   int     `json:"passed"`
+			PassRate float64 `json:"pass_rate"`
+		}{report, report.Passed(), report.PassRate()}); err != nil {
+			return err
+		}
+	} else if err := printEvalReport(report, *verbose); err != nil {
+		return err
+	}
+
+	if report.PassRate() < *minPassRate {
+		return fmt.Errorf("pass rate %.1f%% is below %.1f%%", report.PassRate()*100, *minPassRate*100)
+	}
+	return nil
+}
+
+// evalExecutor creates an executor that runs scripts in dir without streaming their output
+func (a *App) evalExecutor(dir string) (chat.ScriptExecutor, error) {
+	timeout := a.cfg.ExecTimeout
+	if timeout == 0 {
+		timeout = evalTimeout
+	}
+	return chat.NewScriptExecutor(a.cfg.Shell, &chat.ExecOptions{
+		Dir:            dir,
+		MaxOutputBytes: a.cfg.MaxOutputBytes,
+		Timeout:        timeout,
+		Limits: chat.ResourceLimits{
+			CPUSeconds:     a.cfg.LimitCPUSeconds,
+			AddressSpaceMB: a.cfg.LimitAddressSpaceMB,
+			OpenFiles:      a.cfg.LimitOpenFiles,
+			Processes:      a.cfg.LimitProcesses,
+		},
+	})
+}
+
+// printEvalReport prints a table of the task results, the failures and scripts of failed tasks, and the pass rate
+func printEvalReport(report *eval.Report, verbose bool) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SUITE\tTASK\tRESULT\tTIME")
+	for _, result := range report.Results {
+		status := "FAIL"
+		if result.Passed {
+			status = "pass"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Suite, result.Task, status, result.Duration.Round(time.Millisecond))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, result := range report.Results {
+		if result.Passed && !verbose {
+			continue
+		}
+		fmt.Printf("\n%s/%s:\n", result.Suite, result.Task)
+		if result.Script != "" {
+			fmt.Printf("  script: %s\n", strings.ReplaceAll(result.Script, "\n", "\n          "))
+		}
+		for _, failure := range result.Failures {
+			fmt.Printf("  - %s\n", failure)
+		}
+	}
+
+	fmt.Printf("\nModel: %s\n", report.Model)
+	fmt.Printf("Passed %d/%d tasks (%.1f%%)\n", report.Passed(), len(report.Results), report.PassRate()*100)
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmc/langchaingo/memory"
+
+	"github.com/blysin/autocmdr/pkg/chat"
+)
+
+// runExplainCommand handles "autocmdr explain <command>", printing what the command does without running it
+func (a *App) runExplainCommand(args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the explanation as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	command := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if command == "" && stdinIsPiped() {
+		var err error
+		if command, err = readQuery(os.Stdin); err != nil {
+			return fmt.Errorf("failed to read command from stdin: %w", err)
+		}
+	}
+	if command == "" {
+		return fmt.Errorf("missing command to explain")
+	}
+	if err := a.cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if a.logger.GetLevel() == logrus.InfoLevel {
+		a.logger.SetLevel(logrus.WarnLevel)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.setupShutdownHandler(cancel, syscall.SIGINT, syscall.SIGTERM)
+
+	assistant := chat.NewCliAssistant(a.chatOptions(), a.initExecutor(), a.logger)
+	assistant.SetModel(a.initLLM(), memory.NewConversationBuffer())
+	explanation, err := assistant.Explain(ctx, command)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(explanation)
+	}
+	chat.PrintExplanation(command, explanation)
+	return nil
+}
@@ -3,26 +3,53 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
-	"github.com/tmc/langchaingo/llms/ollama"
-	"github.com/tmc/langchaingo/memory"
+	"github.com/tmc/langchaingo/llms"
 
+	"github.com/blysin/autocmdr/pkg/audit"
 	"github.com/blysin/autocmdr/pkg/chat"
 	"github.com/blysin/autocmdr/pkg/config"
+	"github.com/blysin/autocmdr/pkg/policy"
 	"github.com/blysin/autocmdr/pkg/prompts"
+	"github.com/blysin/autocmdr/pkg/provider"
+	"github.com/blysin/autocmdr/pkg/risk"
+	"github.com/blysin/autocmdr/pkg/session"
+	"github.com/blysin/autocmdr/pkg/sysinfo"
 	"github.com/blysin/autocmdr/pkg/version"
 )
 
+// Exit codes used in one-shot mode
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitNoScript = 2
+	exitRefused  = 3
+)
+
+// environmentTimeout bounds how long collecting the environment context can delay startup
+const environmentTimeout = 5 * time.Second
+
 // App represents the application.
 type App struct {
-	logger *logrus.Logger
-	cfg    *config.Config
+	logger    *logrus.Logger
+	cfg       *config.Config
+	query     string
+	exec      bool
+	force     bool
+	sessionID string
+	resume    bool
+	plan      bool
+	agent     bool
 }
 
 // NewApp creates a new App instance.
@@ -36,10 +63,24 @@ type Args struct {
 	View     bool
 	Prompt   bool
 	Version  bool
+	Provider string
 	Model    string
 	Server   string
 	Token    string
+	Shell    string
 	LogLevel string
+	Exec     bool
+	Force    bool
+	Session  string
+	Resume   bool
+	Timeout  time.Duration
+	AutoFix  bool
+	Plan     bool
+	Agent    bool
+	Template string
+	Rest     []string
+	// Query is set when the arguments follow --, so that they are always a one-shot request
+	Query bool
 }
 
 // ParseArgs parses command line arguments and returns them as a struct.
@@ -49,11 +90,27 @@ func (a *App) ParseArgs() *Args {
 	flag.BoolVar(&args.View, "view", false, "View current configuration")
 	flag.BoolVar(&args.Prompt, "prompt", false, "View system prompt")
 	flag.BoolVar(&args.Version, "version", false, "Show version information")
+	flag.StringVar(&args.Provider, "p", "", "LLM provider ("+strings.Join(provider.Names(), ", ")+")")
 	flag.StringVar(&args.Model, "m", "", "Model name")
 	flag.StringVar(&args.Server, "u", "", "Server URL")
 	flag.StringVar(&args.Token, "t", "", "API token")
+	flag.StringVar(&args.Shell, "shell", "", "Shell used to execute scripts (bash, sh, zsh, fish, pwsh, powershell)")
 	flag.StringVar(&args.LogLevel, "log-level", "", "Log level (debug, info, warn, error)")
+	flag.BoolVar(&args.Exec, "exec", false, "Execute the generated script in one-shot mode")
+	flag.BoolVar(&args.Force, "force", false, "Allow -exec to run destructive or privileged scripts")
+	flag.StringVar(&args.Session, "session", "", "Resume or create the chat session with this ID")
+	flag.BoolVar(&args.Resume, "resume", false, "Resume the most recent chat session")
+	flag.DurationVar(&args.Timeout, "timeout", 0, "Stop executed scripts after this duration, e.g. 30s or 5m")
+	flag.BoolVar(&args.AutoFix, "auto-fix", false, "Ask the AI to correct scripts that fail")
+	flag.BoolVar(&args.Plan, "plan", false, "Start the chat in plan mode, running multi-step plans step by step")
+	flag.StringVar(&args.Template, "template", "", "System prompt template, built-in or from the prompts directory")
+	flag.BoolVar(&args.Agent, "agent", false, "Let the AI inspect the system with read-only tools before answering")
+	flag.Usage = usage
 	flag.Parse()
+	args.Rest = flag.Args()
+	if n := len(os.Args) - len(args.Rest); n > 1 && os.Args[n-1] == "--" {
+		args.Query = true
+	}
 	return &args
 }
 
@@ -80,11 +137,31 @@ func (a *App) handleFlags(args *Args) (continueChat bool) {
 	if args.LogLevel != "" {
 		a.cfg.LogLevel = args.LogLevel
 	}
+	if args.Shell != "" && !args.Init {
+		a.cfg.Shell = args.Shell
+	}
+	if args.Timeout != 0 && !args.Init {
+		a.cfg.ExecTimeout = args.Timeout
+	}
+	if args.AutoFix && !args.Init {
+		a.cfg.AutoFix = true
+	}
+	if args.Template != "" && !args.Init {
+		a.cfg.Template = args.Template
+	}
 
 	a.logger = setupLogger(a.cfg.LogLevel)
 
+	// Subcommands take precedence over one-shot requests that start with their names
+	if len(args.Rest) > 0 && !args.Query {
+		if cmd := findCommand(args.Rest[0]); cmd != nil {
+			a.runCommand(cmd, args.Rest[1:])
+			return continueChat
+		}
+	}
+
 	if args.Init {
-		a.handleInit(args.Model, args.Server, args.Token)
+		a.handleInit(args)
 		return continueChat
 	}
 
@@ -101,6 +178,30 @@ func (a *App) handleFlags(args *Args) (continueChat bool) {
 	if err = a.cfg.Validate(); err != nil {
 		a.logger.WithError(err).Fatal("Invalid configuration")
 	}
+
+	if args.Session != "" {
+		if err = session.ValidateID(args.Session); err != nil {
+			a.logger.WithError(err).Fatal("Invalid session")
+		}
+	}
+	a.sessionID = args.Session
+	a.resume = args.Resume
+	a.plan = args.Plan
+	a.agent = args.Agent
+
+	a.query = strings.TrimSpace(strings.Join(args.Rest, " "))
+	if a.query == "" && stdinIsPiped() {
+		a.query, err = readQuery(os.Stdin)
+		if err != nil {
+			a.logger.WithError(err).Fatal("Failed to read prompt from stdin")
+		}
+	}
+	a.exec = args.Exec
+	a.force = args.Force
+	if a.query != "" && args.LogLevel == "" && a.logger.GetLevel() == logrus.InfoLevel {
+		// Keep stderr quiet when used from scripts
+		a.logger.SetLevel(logrus.WarnLevel)
+	}
 	continueChat = true
 	return continueChat
 }
@@ -110,15 +211,30 @@ func (a *App) showVersion() {
 	fmt.Println(versionInfo.String())
 }
 
-func (a *App) handleInit(model, server, token string) {
-	if model != "" {
-		a.cfg.Model = model
+func (a *App) handleInit(args *Args) {
+	if args.Provider != "" {
+		a.cfg.Provider = args.Provider
+	}
+	if args.Model != "" {
+		a.cfg.Model = args.Model
+	}
+	if args.Server != "" {
+		a.cfg.ServerURL = args.Server
+	}
+	if args.Token != "" {
+		a.cfg.Token = args.Token
+	}
+	if args.Shell != "" {
+		a.cfg.Shell = args.Shell
+	}
+	if args.Timeout != 0 {
+		a.cfg.ExecTimeout = args.Timeout
 	}
-	if server != "" {
-		a.cfg.ServerURL = server
+	if args.AutoFix {
+		a.cfg.AutoFix = true
 	}
-	if token != "" {
-		a.cfg.Token = token
+	if args.Template != "" {
+		a.cfg.Template = args.Template
 	}
 
 	if err := a.cfg.Save(); err != nil {
@@ -129,15 +245,34 @@ func (a *App) handleInit(model, server, token string) {
 
 func (a *App) showConfig() {
 	fmt.Printf("Configuration:\n")
+	fmt.Printf("  Provider: %s\n", a.cfg.Provider)
 	fmt.Printf("  Model: %s\n", a.cfg.Model)
 	fmt.Printf("  Server URL: %s\n", a.cfg.ServerURL)
 	fmt.Printf("  Token: %s\n", maskToken(a.cfg.Token))
+	fmt.Printf("  Shell: %s\n", displayShell(a.cfg.Shell))
+	fmt.Printf("  Max Output Bytes: %d\n", a.cfg.MaxOutputBytes)
+	fmt.Printf("  Exec Timeout: %s\n", displayTimeout(a.cfg.ExecTimeout))
+	fmt.Printf("  Resource Limits: %s\n", displayLimits(a.cfg))
+	fmt.Printf("  Auto-fix: %t (max %d attempts)\n", a.cfg.AutoFix, a.cfg.MaxFixAttempts)
+	fmt.Printf("  Max Repair Attempts: %d\n", a.cfg.MaxRepairAttempts)
+	fmt.Printf("  Structured Output: %s\n", a.cfg.StructuredOutput)
+	fmt.Printf("  Context Collectors: %s\n", displayList(a.cfg.ContextCollectors))
+	fmt.Printf("  Template: %s\n", displayTemplate(a.cfg.Template))
+	fmt.Printf("  Language: %s\n", a.cfg.Language)
 	fmt.Printf("  Log Level: %s\n", a.cfg.LogLevel)
 	fmt.Printf("  Config Directory: %s\n", a.cfg.ConfigDir)
 }
 
 func (a *App) showPrompt() {
+	shell := a.initExecutor().GetShell()
 	loader := prompts.NewLoader()
+	loader.SetShell(shell)
+	loader.SetLanguage(a.cfg.Language)
+	loader.SetEnvironment(a.initEnvironment(shell))
+	loader.SetTemplateDirs(prompts.TemplateDirs(a.cfg.ConfigDir, a.workDir())...)
+	if err := loader.SetTemplate(a.cfg.Template); err != nil {
+		a.logger.WithError(err).Fatal("Failed to load prompt template")
+	}
 	systemPrompt := loader.LoadSystemPrompt()
 	fmt.Println(systemPrompt)
 }
@@ -146,22 +281,103 @@ func (a *App) runChatSession() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	a.setupShutdownHandler(cancel)
+	// Ctrl-C is handled by readline at the prompt, cancels the running turn while it waits for the model or for
+	// an answer, and stops only the running script during execution
+	a.setupShutdownHandler(cancel, syscall.SIGTERM)
 
 	llm := a.initLLM()
-	chatMemory := memory.NewConversationWindowBuffer(10)
-	assistant := chat.NewCliAssistant(chat.DefaultChatOptions(), a.logger)
+	a.warnAgentMode()
+	options := a.chatOptions()
+	options.PlanMode = a.plan
+	options.AgentMode = a.agent
+	executor := a.initExecutor()
+	assistant := chat.NewCliAssistant(options, executor, a.logger)
+	assistant.SetEnvironment(a.initEnvironment(executor.GetShell()))
+	a.initTemplate(assistant)
+	assistant.SetPolicy(a.initPolicy())
+
+	store := session.NewStore(a.cfg.ConfigDir)
+	chatSession := a.openSession(store, options)
+	assistant.SetSession(chatSession, store)
+	assistant.SetAuditLog(audit.NewLog(a.cfg.ConfigDir))
 
-	a.logger.Info("Starting chat session")
-	if err := assistant.Run(ctx, llm, chatMemory); err != nil {
+	a.logger.WithFields(logrus.Fields{
+		"session":  chatSession.ID,
+		"messages": len(chatSession.Messages),
+	}).Info("Starting chat session")
+	if err := assistant.Run(ctx, llm, chatSession.Memory); err != nil {
 		a.logger.WithError(err).Fatal("Chat session failed")
 	}
 	a.logger.Info("Chat session ended")
 }
 
-func (a *App) setupShutdownHandler(cancel context.CancelFunc) {
+// runOneShot sends a single prompt, prints the generated script and returns the process exit code
+func (a *App) runOneShot() int {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a.setupShutdownHandler(cancel, syscall.SIGINT, syscall.SIGTERM)
+
+	llm := a.initLLM()
+	a.warnAgentMode()
+	executor := a.initExecutor()
+	options := a.chatOptions()
+	options.AgentMode = a.agent
+	assistant := chat.NewCliAssistant(options, executor, a.logger)
+	assistant.SetEnvironment(a.initEnvironment(executor.GetShell()))
+	a.initTemplate(assistant)
+	assistant.SetPolicy(a.initPolicy())
+	assistant.SetAuditLog(audit.NewLog(a.cfg.ConfigDir))
+	defer assistant.FlushAudit()
+
+	result, err := assistant.RunOnce(ctx, llm, a.query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if !result.Success {
+		fmt.Fprintf(os.Stderr, "AI did not provide a script: %s\n", result.Script)
+		return exitNoScript
+	}
+
+	script := strings.TrimSpace(result.Script)
+	if !a.exec {
+		fmt.Println(script)
+		return exitOK
+	}
+
+	assessment := risk.Analyze(script)
+	if assessment.Level >= risk.Destructive && !a.force {
+		fmt.Fprintln(os.Stderr, script)
+		fmt.Fprintf(os.Stderr, "Refusing to execute %s script without -force\n", assessment.Level)
+		return exitRefused
+	}
+
+	execResult, err := assistant.ExecuteApproved(ctx, script, chat.ApprovalExecFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Execution error: %v\n", err)
+	}
+	return execExitCode(execResult, err)
+}
+
+// execExitCode returns the process exit code for a script executed in one-shot mode:
+// the exit code of the script, exitRefused when the policy denied it, and exitError when it could not run
+// or was stopped before exiting
+func execExitCode(result *chat.ExecutionResult, err error) int {
+	var denied *policy.DeniedError
+	switch {
+	case errors.As(err, &denied):
+		return exitRefused
+	case err != nil, result.ExitCode < 0:
+		return exitError
+	}
+	return result.ExitCode
+}
+
+func (a *App) setupShutdownHandler(cancel context.CancelFunc, signals ...os.Signal) {
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, signals...)
 	go func() {
 		<-sigChan
 		a.logger.Info("Received shutdown signal")
@@ -169,24 +385,97 @@ func (a *App) setupShutdownHandler(cancel context.CancelFunc) {
 	}()
 }
 
-func (a *App) initLLM() *ollama.LLM {
-	options := []ollama.Option{
-		ollama.WithServerURL(a.cfg.ServerURL),
-		ollama.WithModel(a.cfg.Model),
+func (a *App) initExecutor() chat.ScriptExecutor {
+	executor, err := chat.NewScriptExecutor(a.cfg.Shell, &chat.ExecOptions{
+		Stdout:         os.Stdout,
+		Stderr:         os.Stderr,
+		MaxOutputBytes: a.cfg.MaxOutputBytes,
+		Timeout:        a.cfg.ExecTimeout,
+		Limits: chat.ResourceLimits{
+			CPUSeconds:     a.cfg.LimitCPUSeconds,
+			AddressSpaceMB: a.cfg.LimitAddressSpaceMB,
+			OpenFiles:      a.cfg.LimitOpenFiles,
+			Processes:      a.cfg.LimitProcesses,
+		},
+	})
+	if err != nil {
+		a.logger.WithError(err).Fatal("Failed to initialize script executor")
+	}
+
+	a.logger.WithField("shell", executor.GetShell()).Debug("Script executor initialized")
+	return executor
+}
+
+// initEnvironment collects the facts about the environment that are added to the system prompt
+func (a *App) initEnvironment(shell string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), environmentTimeout)
+	defer cancel()
+
+	facts, err := sysinfo.Collect(ctx, a.cfg.ContextCollectors, &sysinfo.Options{Shell: shell})
+	if err != nil {
+		a.logger.WithError(err).Warn("Failed to collect environment context")
+		return ""
+	}
+	a.logger.WithField("facts", len(facts)).Debug("Environment context collected")
+	return sysinfo.Format(facts)
+}
+
+// initTemplate selects the configured prompt template, searching the user-wide and project template directories
+func (a *App) initTemplate(assistant *chat.CliAssistant) {
+	assistant.SetTemplateDirs(prompts.TemplateDirs(a.cfg.ConfigDir, a.workDir())...)
+	if err := assistant.SetTemplate(a.cfg.Template); err != nil {
+		a.logger.WithError(err).Fatal("Failed to load prompt template")
 	}
+}
 
-	if a.cfg.Token != "" {
-		a.logger.Debug("Using authentication token")
+// workDir returns the current directory, where project configuration is looked up
+func (a *App) workDir() string {
+	workDir, err := os.Getwd()
+	if err != nil {
+		a.logger.WithError(err).Fatal("Failed to get working directory")
 	}
+	return workDir
+}
 
-	llm, err := ollama.New(options...)
+func (a *App) initPolicy() *policy.Policy {
+	p, err := policy.Load(a.cfg.ConfigDir, a.workDir())
+	if err != nil {
+		a.logger.WithError(err).Fatal("Failed to load policy")
+	}
+
+	a.logger.WithField("sources", p.Sources).Debug("Policy loaded")
+	return p
+}
+
+// chatOptions returns the chat options set in the configuration
+func (a *App) chatOptions() *chat.Options {
+	options := chat.DefaultChatOptions()
+	options.AutoFix = a.cfg.AutoFix
+	options.MaxFixAttempts = a.cfg.MaxFixAttempts
+	options.MaxRepairAttempts = a.cfg.MaxRepairAttempts
+	options.Language = a.cfg.Language
+	return options
+}
+
+// warnAgentMode warns when agent mode is requested with a provider that cannot call tools
+func (a *App) warnAgentMode() {
+	if a.agent && !provider.SupportsTools(a.cfg.Provider) {
+		a.logger.Warnf("The %s provider cannot call tools, so agent mode has no effect; "+
+			"use the openai provider with an OpenAI-compatible endpoint such as http://localhost:11434/v1 instead", a.cfg.Provider)
+	}
+}
+
+func (a *App) initLLM() llms.Model {
+	llm, err := provider.New(a.cfg)
 	if err != nil {
 		a.logger.WithError(err).Fatal("Failed to initialize LLM")
 	}
 
 	a.logger.WithFields(logrus.Fields{
+		"provider":   a.cfg.Provider,
 		"model":      a.cfg.Model,
 		"server_url": a.cfg.ServerURL,
+		"auth":       a.cfg.Token != "",
 	}).Info("LLM initialized successfully")
 
 	return llm
@@ -198,9 +487,47 @@ func main() {
 	if !continueChat {
 		return
 	}
+	if app.query != "" {
+		os.Exit(app.runOneShot())
+	}
 	app.runChatSession()
 }
 
+// usage prints command line usage
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage:\n")
+	fmt.Fprintf(out, "  autocmdr [flags]                 Start an interactive chat session\n")
+	fmt.Fprintf(out, "  autocmdr [flags] \"<request>\"     Print the script for a single request\n")
+	fmt.Fprintf(out, "  echo \"<request>\" | autocmdr      Read the request from stdin\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  autocmdr %s\n", cmd.usage)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Requests that start with a subcommand name must be quoted or follow --, as in autocmdr -- explain why load is high.\n")
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+// stdinIsPiped reports whether stdin is a pipe or file rather than a terminal
+func stdinIsPiped() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// readQuery reads the one-shot request from r
+func readQuery(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 // setupLogger configures the logger based on the log level
 func setupLogger(logLevel string) *logrus.Logger {
 	logger := logrus.New()
@@ -228,6 +555,59 @@ func setupLogger(logLevel string) *logrus.Logger {
 	return logger
 }
 
+// displayShell returns the configured shell or a note that it is auto-detected
+func displayShell(shell string) string {
+	if shell == "" {
+		return fmt.Sprintf("(auto: %s)", chat.DetectShell())
+	}
+	return shell
+}
+
+// displayTimeout returns the execution timeout for display purposes
+func displayTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "(none)"
+	}
+	return timeout.String()
+}
+
+// displayList joins names for display, or shows (none) when there are none
+func displayList(names []string) string {
+	if len(names) == 0 {
+		return "(none)"
+	}
+	return strings.Join(names, ", ")
+}
+
+// displayTemplate returns the configured prompt template or the default one
+func displayTemplate(template string) string {
+	if template == "" {
+		return prompts.DefaultTemplate
+	}
+	return template
+}
+
+// displayLimits returns the configured resource limits for display purposes
+func displayLimits(cfg *config.Config) string {
+	var limits []string
+	if cfg.LimitCPUSeconds > 0 {
+		limits = append(limits, fmt.Sprintf("cpu=%ds", cfg.LimitCPUSeconds))
+	}
+	if cfg.LimitAddressSpaceMB > 0 {
+		limits = append(limits, fmt.Sprintf("memory=%dMB", cfg.LimitAddressSpaceMB))
+	}
+	if cfg.LimitOpenFiles > 0 {
+		limits = append(limits, fmt.Sprintf("files=%d", cfg.LimitOpenFiles))
+	}
+	if cfg.LimitProcesses > 0 {
+		limits = append(limits, fmt.Sprintf("processes=%d", cfg.LimitProcesses))
+	}
+	if len(limits) == 0 {
+		return "(none)"
+	}
+	return strings.Join(limits, " ")
+}
+
 // maskToken masks the token for display purposes
 func maskToken(token string) string {
 	if token == "" {
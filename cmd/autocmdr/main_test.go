@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blysin/autocmdr/pkg/chat"
+	"github.com/blysin/autocmdr/pkg/policy"
+)
+
+func TestExecExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   *chat.ExecutionResult
+		err      error
+		expected int
+	}{
+		{name: "success", result: &chat.ExecutionResult{Success: true}, expected: exitOK},
+		{name: "script failed", result: &chat.ExecutionResult{ExitCode: 7}, expected: 7},
+		{name: "timed out", result: &chat.ExecutionResult{ExitCode: -1, FailureReason: chat.FailureTimeout}, expected: exitError},
+		{name: "denied by policy", err: &policy.DeniedError{Rule: policy.Rule{Name: "no-rm"}, Command: "rm"}, expected: exitRefused},
+		{name: "could not run", err: errors.New("cannot execute script with shell fish"), expected: exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := execExitCode(tt.result, tt.err); got != tt.expected {
+				t.Errorf("expected exit code %d but got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReadQuery(t *testing.T) {
+	query, err := readQuery(strings.NewReader("  show disk usage\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query != "show disk usage" {
+		t.Errorf("expected %q but got %q", "show disk usage", query)
+	}
+}
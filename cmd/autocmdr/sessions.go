@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/blysin/autocmdr/pkg/chat"
+	"github.com/blysin/autocmdr/pkg/session"
+)
+
+// runSessionsCommand handles "autocmdr sessions list" and "autocmdr sessions delete <id>"
+func (a *App) runSessionsCommand(args []string) error {
+	store := session.NewStore(a.cfg.ConfigDir)
+
+	if len(args) == 0 {
+		return fmt.Errorf("missing sessions action")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return listSessions(store)
+	case "delete", "rm":
+		if len(args) < 2 {
+			return fmt.Errorf("missing session id")
+		}
+		for _, id := range args[1:] {
+			if err := store.Delete(id); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted session %s\n", id)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown sessions action: %s", args[0])
+	}
+}
+
+// listSessions prints the stored sessions as a table
+func listSessions(store *session.Store) error {
+	summaries, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(summaries) == 0 {
+		fmt.Println("No sessions found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUPDATED\tMESSAGES\tEXECUTIONS\tTITLE")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n",
+			s.ID, s.UpdatedAt.Format("2006-01-02 15:04"), s.Messages, s.Executions, s.Title)
+	}
+	return w.Flush()
+}
+
+// openSession loads the session selected by -session or -resume, or starts a new one
+func (a *App) openSession(store *session.Store, options *chat.Options) *chat.Session {
+	switch {
+	case a.resume:
+		s, err := store.Latest(options)
+		if err == nil {
+			return s
+		}
+		if !errors.Is(err, session.ErrNotFound) {
+			a.logger.WithError(err).Fatal("Failed to resume session")
+		}
+		a.logger.Warn("No previous session found, starting a new one")
+	case a.sessionID != "":
+		s, err := store.Load(a.sessionID, options)
+		if err == nil {
+			return s
+		}
+		if !errors.Is(err, session.ErrNotFound) {
+			a.logger.WithError(err).Fatal("Failed to load session")
+		}
+		return chat.NewSession(a.sessionID, options)
+	}
+	return chat.NewSession(session.NewID(), options)
+}
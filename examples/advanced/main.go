@@ -21,6 +21,7 @@ func main() {
 
 	// Create custom configuration
 	cfg := &config.Config{
+		Provider:  "ollama",
 		Model:     "custom-model",
 		ServerURL: "http://localhost:11434",
 		Token:     "",
@@ -69,7 +70,7 @@ func main() {
 	logger.WithField("prompt_length", len(systemPrompt)).Debug("Loaded system prompt")
 
 	// Create chat assistant with custom options
-	assistant := chat.NewCliAssistant(chatOptions, logger)
+	assistant := chat.NewCliAssistant(chatOptions, nil, logger)
 
 	// Demonstrate programmatic script execution
 	logger.Info("Demonstrating programmatic script execution")
@@ -85,6 +86,20 @@ func main() {
 		fmt.Printf("Output: %s\n", result.Output)
 	}
 
+	// Demonstrate programmatic script generation
+	logger.Info("Demonstrating programmatic script generation")
+	assistant.SetModel(llm, chatMemory)
+	generated, err := assistant.ProcessInput(ctx, "show the current date and time")
+	if err != nil {
+		logger.WithError(err).Error("Failed to generate script")
+	} else {
+		logger.WithFields(logrus.Fields{
+			"success":        generated.Success,
+			"multiple_lines": generated.MultipleLines,
+		}).Info("Script generated")
+		fmt.Printf("Script: %s\n", generated.Script)
+	}
+
 	// Start interactive chat session
 	logger.Info("Starting interactive chat session")
 	if err := assistant.Run(ctx, llm, chatMemory); err != nil {
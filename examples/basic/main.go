@@ -7,8 +7,8 @@ import (
 
 	"github.com/blysin/autocmdr/pkg/chat"
 	"github.com/blysin/autocmdr/pkg/config"
+	"github.com/blysin/autocmdr/pkg/provider"
 	"github.com/sirupsen/logrus"
-	"github.com/tmc/langchaingo/llms/ollama"
 	"github.com/tmc/langchaingo/memory"
 )
 
@@ -31,13 +31,8 @@ func main() {
 	logger := logrus.New()
 	logger.SetLevel(logrus.InfoLevel)
 
-	// Initialize LLM
-	options := []ollama.Option{
-		ollama.WithServerURL(cfg.ServerURL),
-		ollama.WithModel(cfg.Model),
-	}
-
-	llm, err := ollama.New(options...)
+	// Initialize LLM for the configured provider
+	llm, err := provider.New(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize LLM: %v", err)
 	}
@@ -47,7 +42,7 @@ func main() {
 
 	// Create chat assistant
 	chatOptions := chat.DefaultChatOptions()
-	assistant := chat.NewCliAssistant(chatOptions, logger)
+	assistant := chat.NewCliAssistant(chatOptions, nil, logger)
 
 	// Start chat session
 	ctx := context.Background()
@@ -0,0 +1,166 @@
+// Package audit keeps an append-only JSON Lines record of generated and executed scripts.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blysin/autocmdr/pkg/chat"
+)
+
+// FileName is the name of the audit log in the config directory
+const FileName = "audit.jsonl"
+
+// maxLineSize bounds the size of a single record when reading the log
+const maxLineSize = 16 << 20
+
+// Log appends audit entries to a JSON Lines file
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Filter selects audit entries. Zero fields match everything.
+type Filter struct {
+	Since    time.Time
+	Until    time.Time
+	ExitCode *int
+	Command  string
+}
+
+// NewLog creates a log in configDir
+func NewLog(configDir string) *Log {
+	return &Log{path: filepath.Join(configDir, FileName)}
+}
+
+// Path returns the path of the log file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append writes an entry as a single line at the end of the log
+func (l *Log) Append(entry *chat.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Query returns the entries that match the filter, oldest first.
+// Lines that cannot be decoded, such as a record cut short by a crash, are skipped.
+func (l *Log) Query(filter Filter) ([]*chat.AuditEntry, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var entries []*chat.AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		entry := &chat.AuditEntry{}
+		if err := json.Unmarshal(line, entry); err != nil {
+			continue
+		}
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// Match reports whether an entry passes the filter
+func (f Filter) Match(entry *chat.AuditEntry) bool {
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	if f.ExitCode != nil && !matchExitCode(entry, *f.ExitCode) {
+		return false
+	}
+	if f.Command != "" && !strings.Contains(strings.ToLower(Command(entry)), strings.ToLower(f.Command)) {
+		return false
+	}
+	return true
+}
+
+// matchExitCode reports whether any execution of the entry exited with code
+func matchExitCode(entry *chat.AuditEntry, code int) bool {
+	for _, execution := range executions(entry) {
+		if execution.ExitCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// executions returns every execution of the entry, one per executed step for plans
+func executions(entry *chat.AuditEntry) []*chat.ExecutionResult {
+	if len(entry.Executions) > 0 {
+		return entry.Executions
+	}
+	if entry.Execution != nil {
+		return []*chat.ExecutionResult{entry.Execution}
+	}
+	return nil
+}
+
+// Command returns the command that was executed, or the generated script if nothing ran.
+// The commands of a plan are joined with "; ".
+func Command(entry *chat.AuditEntry) string {
+	var commands []string
+	for _, execution := range executions(entry) {
+		commands = append(commands, execution.Command)
+	}
+	if len(commands) == 0 && entry.Result != nil {
+		if len(entry.Result.Steps) == 0 {
+			return entry.Result.Script
+		}
+		for _, step := range entry.Result.Steps {
+			commands = append(commands, step.Script)
+		}
+	}
+	return strings.Join(commands, "; ")
+}
@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blysin/autocmdr/pkg/chat"
+)
+
+func TestLogAppendAndQuery(t *testing.T) {
+	log := NewLog(t.TempDir())
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []*chat.AuditEntry{
+		{
+			Time:      base,
+			SessionID: "s1",
+			Prompt:    "list files",
+			Response:  `{"success": true, "multipleLines": false, "script": "ls -la"}`,
+			Result:    &chat.AssistantResult{Success: true, Script: "ls -la"},
+			Confirmed: true,
+			Execution: &chat.ExecutionResult{Success: true, Command: "ls -la", ExitCode: 0},
+		},
+		{
+			Time:      base.Add(24 * time.Hour),
+			Prompt:    "remove logs",
+			Result:    &chat.AssistantResult{Success: true, Script: "rm -rf /var/log/app"},
+			Confirmed: false,
+		},
+		{
+			Time:      base.Add(48 * time.Hour),
+			Prompt:    "check disk",
+			Result:    &chat.AssistantResult{Success: true, Script: "df -h /missing"},
+			Confirmed: true,
+			Execution: &chat.ExecutionResult{Command: "df -h /missing", ExitCode: 1, Stderr: "df: /missing: No such file"},
+		},
+		{
+			Time:   base.Add(72 * time.Hour),
+			Prompt: "build plan",
+			Result: &chat.AssistantResult{Success: true, Steps: []chat.PlanStep{
+				{Description: "create dir", Script: "mkdir out"},
+				{Description: "create file", Script: "touch out/x"},
+			}},
+			Confirmed: true,
+			Execution: &chat.ExecutionResult{Command: "touch out/x", ExitCode: 2},
+			Executions: []*chat.ExecutionResult{
+				{Success: true, Command: "mkdir out", ExitCode: 0},
+				{Command: "touch out/x", ExitCode: 2},
+			},
+		},
+	}
+	for _, entry := range entries {
+		if err := log.Append(entry); err != nil {
+			t.Fatalf("failed to append entry: %v", err)
+		}
+	}
+
+	exitOne := 1
+	exitZero := 0
+	exitTwo := 2
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{name: "all", filter: Filter{}, expected: []string{"list files", "remove logs", "check disk", "build plan"}},
+		{name: "since", filter: Filter{Since: base.Add(time.Hour)}, expected: []string{"remove logs", "check disk", "build plan"}},
+		{name: "until", filter: Filter{Until: base.Add(24 * time.Hour)}, expected: []string{"list files"}},
+		{name: "exit code", filter: Filter{ExitCode: &exitOne}, expected: []string{"check disk"}},
+		{name: "exit code zero skips unexecuted", filter: Filter{ExitCode: &exitZero}, expected: []string{"list files", "build plan"}},
+		{name: "exit code of a plan step", filter: Filter{ExitCode: &exitTwo}, expected: []string{"build plan"}},
+		{name: "command of a plan step", filter: Filter{Command: "mkdir"}, expected: []string{"build plan"}},
+		{name: "command matches unexecuted script", filter: Filter{Command: "RM -RF"}, expected: []string{"remove logs"}},
+		{name: "no match", filter: Filter{Command: "reboot"}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := log.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to query: %v", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %d entries but got %d", len(tt.expected), len(got))
+			}
+			for i, entry := range got {
+				if entry.Prompt != tt.expected[i] {
+					t.Errorf("entry %d: expected prompt %q but got %q", i, tt.expected[i], entry.Prompt)
+				}
+			}
+		})
+	}
+}
+
+func TestLogRoundTripsEntry(t *testing.T) {
+	log := NewLog(t.TempDir())
+	entry := &chat.AuditEntry{
+		Time:      time.Now().UTC().Truncate(time.Second),
+		SessionID: "s1",
+		Prompt:    "show uptime",
+		Response:  "<think>easy</think>{\"success\": true, \"script\": \"uptime\"}",
+		Result:    &chat.AssistantResult{Success: true, Script: "uptime"},
+		Confirmed: true,
+		Execution: &chat.ExecutionResult{Success: true, Command: "uptime", Stdout: "up 3 days\n", Duration: "5ms"},
+	}
+	if err := log.Append(entry); err != nil {
+		t.Fatalf("failed to append entry: %v", err)
+	}
+
+	got, err := log.Query(Filter{})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 entry but got %d", len(got))
+	}
+	if !got[0].Time.Equal(entry.Time) || got[0].Response != entry.Response || got[0].Execution.Stdout != "up 3 days\n" {
+		t.Errorf("entry did not round trip: %+v", got[0])
+	}
+}
+
+func TestLogSkipsMalformedLines(t *testing.T) {
+	log := NewLog(t.TempDir())
+	if err := log.Append(&chat.AuditEntry{Prompt: "first"}); err != nil {
+		t.Fatalf("failed to append entry: %v", err)
+	}
+
+	f, err := os.OpenFile(log.Path(), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	if _, err := f.WriteString("{\"prompt\": \"cut sh\n"); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+	_ = f.Close()
+
+	if err := log.Append(&chat.AuditEntry{Prompt: "second"}); err != nil {
+		t.Fatalf("failed to append entry: %v", err)
+	}
+
+	got, err := log.Query(Filter{})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if len(got) != 2 || got[0].Prompt != "first" || got[1].Prompt != "second" {
+		t.Errorf("expected the two valid entries but got %+v", got)
+	}
+}
+
+func TestQueryMissingLog(t *testing.T) {
+	got, err := NewLog(t.TempDir()).Query(Filter{})
+	if err != nil || got != nil {
+		t.Errorf("expected no entries and no error but got %v, %v", got, err)
+	}
+}
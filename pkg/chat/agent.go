@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/blysin/autocmdr/pkg/tools"
+)
+
+// maxToolRounds limits how many rounds of tool calls the model can make before it must answer
+const maxToolRounds = 8
+
+// runAgent sends input to the model together with the read-only tools, runs the tools it calls
+// without confirmation, and returns its final answer. onToolCall, if set, is told about every call.
+// Like a chain run, the prompt includes the conversation history and the exchange is saved to memory.
+func (c *CliAssistant) runAgent(ctx context.Context, input string, onToolCall func(name, arguments string)) (string, error) {
+	values, err := c.chain.Memory.LoadMemoryVariables(ctx, map[string]any{"input": input})
+	if err != nil {
+		return "", fmt.Errorf("failed to load memory: %w", err)
+	}
+	values["input"] = input
+	prompt, err := c.chain.Prompt.FormatPrompt(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to format prompt: %w", err)
+	}
+
+	available := tools.ReadOnly()
+	definitions := toolDefinitions(available)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, c.promptLoader.AgentInstruction()),
+		llms.TextParts(llms.ChatMessageTypeHuman, prompt.String()),
+	}
+
+	for round := 0; ; round++ {
+		var options []llms.CallOption
+		if round < maxToolRounds {
+			options = append(options, llms.WithTools(definitions))
+		}
+		resp, err := c.llm.GenerateContent(ctx, messages, options...)
+		if err != nil {
+			return "", fmt.Errorf("failed to get AI response: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("failed to get AI response: no choices returned")
+		}
+
+		choice := resp.Choices[0]
+		if len(choice.ToolCalls) == 0 || round >= maxToolRounds {
+			if err := c.chain.Memory.SaveContext(ctx, map[string]any{"input": input}, map[string]any{"text": choice.Content}); err != nil {
+				return "", fmt.Errorf("failed to save memory: %w", err)
+			}
+			return choice.Content, nil
+		}
+
+		request := llms.MessageContent{Role: llms.ChatMessageTypeAI}
+		for _, call := range choice.ToolCalls {
+			request.Parts = append(request.Parts, call)
+		}
+		messages = append(messages, request)
+		for _, call := range choice.ToolCalls {
+			messages = append(messages, llms.MessageContent{
+				Role: llms.ChatMessageTypeTool,
+				Parts: []llms.ContentPart{llms.ToolCallResponse{
+					ToolCallID: call.ID,
+					Name:       toolName(call),
+					Content:    c.callTool(ctx, available, call, onToolCall),
+				}},
+			})
+		}
+	}
+}
+
+// callTool runs a tool the model called and returns its output, or the error for the model to read
+func (c *CliAssistant) callTool(ctx context.Context, available []*tools.Tool, call llms.ToolCall, onToolCall func(name, arguments string)) string {
+	if call.FunctionCall == nil {
+		return "error: the tool call has no function"
+	}
+	name, arguments := call.FunctionCall.Name, call.FunctionCall.Arguments
+	if onToolCall != nil {
+		onToolCall(name, arguments)
+	}
+	c.logger.WithFields(logrus.Fields{"tool": name, "arguments": arguments}).Debug("Calling tool")
+
+	tool := tools.Find(available, name)
+	if tool == nil {
+		return fmt.Sprintf("error: unknown tool %q", name)
+	}
+	output, err := tool.Call(ctx, arguments)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return output
+}
+
+// toolDefinitions describes tools in the form the model expects
+func toolDefinitions(available []*tools.Tool) []llms.Tool {
+	definitions := make([]llms.Tool, len(available))
+	for i, tool := range available {
+		definitions[i] = llms.Tool{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		}
+	}
+	return definitions
+}
+
+// toolName returns the name of the function a tool call invokes
+func toolName(call llms.ToolCall) string {
+	if call.FunctionCall == nil {
+		return ""
+	}
+	return call.FunctionCall.Name
+}
+
+// printToolCall shows a tool call made in agent mode
+func printToolCall(name, arguments string) {
+	fmt.Printf("🔍 %s %s\n", name, arguments)
+}
@@ -0,0 +1,162 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/memory"
+)
+
+// toolCallingLLM is a fake model that replies with its responses in order and records the messages it receives
+type toolCallingLLM struct {
+	responses []*llms.ContentChoice
+	calls     [][]llms.MessageContent
+	toolCount []int
+}
+
+func (m *toolCallingLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *toolCallingLLM) GenerateContent(_ context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+	m.calls = append(m.calls, messages)
+	m.toolCount = append(m.toolCount, len(opts.Tools))
+	if len(m.responses) == 0 {
+		return nil, fmt.Errorf("no more responses")
+	}
+	choice := m.responses[0]
+	m.responses = m.responses[1:]
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{choice}}, nil
+}
+
+// toolCall returns a choice that calls a tool
+func toolCall(id, name, arguments string) *llms.ContentChoice {
+	return &llms.ContentChoice{ToolCalls: []llms.ToolCall{{
+		ID:           id,
+		Type:         "function",
+		FunctionCall: &llms.FunctionCall{Name: name, Arguments: arguments},
+	}}}
+}
+
+func TestProcessInputAgentMode(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("build:\n\tgo build ./...\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		responses []*llms.ContentChoice
+		wantTool  []string
+		script    string
+	}{
+		{
+			name: "inspects before answering",
+			responses: []*llms.ContentChoice{
+				toolCall("call-1", "list_directory", `{"path": "`+dir+`"}`),
+				toolCall("call-2", "read_file_head", `{"path": "`+filepath.Join(dir, "Makefile")+`", "lines": 1}`),
+				{Content: `{"success": true, "script": "make build"}`},
+			},
+			wantTool: []string{"Makefile\t", "build:\n"},
+			script:   "make build",
+		},
+		{
+			name: "tool errors are sent to the model",
+			responses: []*llms.ContentChoice{
+				toolCall("call-1", "rm", `{"path": "/"}`),
+				{Content: `{"success": true, "script": "ls"}`},
+			},
+			wantTool: []string{`error: unknown tool "rm"`},
+			script:   "ls",
+		},
+		{
+			name:      "answers without tools",
+			responses: []*llms.ContentChoice{{Content: `{"success": true, "script": "pwd"}`}},
+			script:    "pwd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &toolCallingLLM{responses: tt.responses}
+			chatMemory := memory.NewConversationBuffer()
+			assistant := newTestAssistant()
+			assistant.options.AgentMode = true
+			assistant.SetModel(llm, chatMemory)
+
+			result, err := assistant.ProcessInput(context.Background(), "build the project")
+			if err != nil {
+				t.Fatalf("ProcessInput() error = %v", err)
+			}
+			if result.Script != tt.script {
+				t.Errorf("Script = %q, want %q", result.Script, tt.script)
+			}
+
+			var outputs []string
+			for _, message := range llm.calls[len(llm.calls)-1] {
+				for _, part := range message.Parts {
+					if response, ok := part.(llms.ToolCallResponse); ok {
+						outputs = append(outputs, response.Content)
+					}
+				}
+			}
+			if len(outputs) != len(tt.wantTool) {
+				t.Fatalf("got %d tool responses, want %d: %q", len(outputs), len(tt.wantTool), outputs)
+			}
+			for i, want := range tt.wantTool {
+				if !strings.Contains(outputs[i], want) {
+					t.Errorf("tool response %d = %q, want it to contain %q", i, outputs[i], want)
+				}
+			}
+
+			messages, err := chatMemory.ChatHistory.Messages(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != 2 || messages[1].GetContent() != tt.responses[len(tt.responses)-1].Content {
+				t.Errorf("memory = %v, want the request and the final answer", messages)
+			}
+		})
+	}
+}
+
+func TestRunAgentLimitsToolRounds(t *testing.T) {
+	var responses []*llms.ContentChoice
+	for i := 0; i < maxToolRounds; i++ {
+		responses = append(responses, toolCall(fmt.Sprintf("call-%d", i), "env_var_names", `{}`))
+	}
+	responses = append(responses, &llms.ContentChoice{Content: `{"success": true, "script": "env"}`})
+
+	llm := &toolCallingLLM{responses: responses}
+	assistant := newTestAssistant()
+	assistant.SetModel(llm, memory.NewConversationBuffer())
+
+	var called []string
+	resp, err := assistant.runAgent(context.Background(), "show env", func(name, _ string) {
+		called = append(called, name)
+	})
+	if err != nil {
+		t.Fatalf("runAgent() error = %v", err)
+	}
+	if resp != `{"success": true, "script": "env"}` {
+		t.Errorf("runAgent() = %q", resp)
+	}
+	if len(called) != maxToolRounds {
+		t.Errorf("called %d tools, want %d", len(called), maxToolRounds)
+	}
+	if last := llm.toolCount[len(llm.toolCount)-1]; last != 0 {
+		t.Errorf("last request offered %d tools, want none", last)
+	}
+	if first := llm.toolCount[0]; first == 0 {
+		t.Error("first request offered no tools")
+	}
+}
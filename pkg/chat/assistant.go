@@ -8,64 +8,96 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"os/signal"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/sirupsen/logrus"
 	"github.com/tmc/langchaingo/chains"
 	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/memory"
 	"github.com/tmc/langchaingo/outputparser"
 	lcprompts "github.com/tmc/langchaingo/prompts"
 	"github.com/tmc/langchaingo/schema"
 
+	"github.com/blysin/autocmdr/pkg/policy"
 	"github.com/blysin/autocmdr/pkg/prompts"
+	"github.com/blysin/autocmdr/pkg/provider"
 	"github.com/blysin/autocmdr/pkg/utils"
+	"github.com/blysin/autocmdr/pkg/validate"
 )
 
 // CliAssistant implements the Assistant interface for CLI interactions
 type CliAssistant struct {
 	options        *Options
 	promptLoader   *prompts.Loader
+	executor       ScriptExecutor
+	policy         *policy.Policy
+	llm            llms.Model
+	chain          *chains.LLMChain
+	session        *Session
+	sessionStore   SessionStore
+	audit          AuditLog
+	turn           *AuditEntry
 	lastExecResult *ExecutionResult
+	lastEdit       *scriptEdit
+	lastPlan       string
 	logger         *logrus.Logger
+	// stdin is the standard input of Run, whose reads Ctrl-C interrupts during a turn
+	stdin *stdinReader
+	// executing is set while a script runs, when Ctrl-C stops only the script
+	executing atomic.Bool
 }
 
-// NewCliAssistant creates a new CLI assistant
-func NewCliAssistant(options *Options, logger *logrus.Logger) *CliAssistant {
+// scriptEdit is a change the user made to a generated script before running it
+type scriptEdit struct {
+	Original string
+	Edited   string
+}
+
+// NewCliAssistant creates a new CLI assistant.
+// If executor is nil, one is created for the shell detected from the environment.
+func NewCliAssistant(options *Options, executor ScriptExecutor, logger *logrus.Logger) *CliAssistant {
 	if options == nil {
 		options = DefaultChatOptions()
 	}
 	if logger == nil {
 		logger = logrus.New()
 	}
+	if executor == nil {
+		var err error
+		executor, err = NewScriptExecutor("", nil)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to detect shell, falling back to bash")
+			executor = NewBashExecutor()
+		}
+	}
+
+	promptLoader := prompts.NewLoader()
+	promptLoader.SetShell(executor.GetShell())
+	promptLoader.SetLanguage(options.Language)
 
 	return &CliAssistant{
 		options:      options,
-		promptLoader: prompts.NewLoader(),
+		promptLoader: promptLoader,
+		executor:     executor,
 		logger:       logger,
 	}
 }
 
 // Run starts the chat session
 func (c *CliAssistant) Run(ctx context.Context, llm llms.Model, chatMemory schema.Memory) error {
-	systemPrompt := c.LoadPrompt()
-	c.logger.WithField("os", runtime.GOOS).Info("Starting chat session")
-
-	chain := chains.LLMChain{
-		Prompt: lcprompts.NewPromptTemplate(
-			systemPrompt,
-			[]string{"history", "input"},
-		),
-		LLM:          llm,
-		Memory:       chatMemory,
-		OutputParser: outputparser.NewSimple(),
-		OutputKey:    "text",
-	}
-
-	reader := bufio.NewReader(os.Stdin)
+	c.SetModel(llm, chatMemory)
+	c.logger.WithFields(logrus.Fields{
+		"os":    runtime.GOOS,
+		"shell": c.executor.GetShell(),
+	}).Info("Starting chat session")
+
+	c.stdin = newStdinReader()
+	reader := bufio.NewReader(c.stdin)
 	c.printWelcomeInfo()
 
 	for {
@@ -77,82 +109,352 @@ func (c *CliAssistant) Run(ctx context.Context, llm llms.Model, chatMemory schem
 			continue
 		}
 
-		resp, err := c.processAIResponse(ctx, &chain, userInput)
-		if err != nil {
-			c.logger.WithError(err).Error("Failed to process AI response")
-			fmt.Printf("Error: %v\n", err)
-			continue
-		}
+		c.handleInput(ctx, reader, userInput)
+	}
 
-		script, err := c.parseScript(resp)
-		if err != nil {
-			c.logger.WithError(err).Error("Failed to parse script")
-			fmt.Printf("\nError: %v\n", err)
-			continue
-		}
+	return nil
+}
+
+// handleInput handles a request from the user, asking for corrected scripts while auto-fix applies.
+// It returns the result of the last executed script, or nil if the last script was not executed.
+func (c *CliAssistant) handleInput(ctx context.Context, reader *bufio.Reader, userInput string) *ExecutionResult {
+	ctx, stop := c.interruptible(ctx)
+	defer stop()
 
-		c.confirmAndExecute(ctx, reader, script)
+	input := userInput
+	if c.options.PlanMode {
+		input = c.promptLoader.PlanInstruction() + userInput
+	}
+	result := c.handleTurn(ctx, reader, userInput, c.withLastEdit(c.withLastExecResult(input)))
+	for attempt := 1; c.needsFix(result) && attempt <= c.options.MaxFixAttempts; attempt++ {
+		fmt.Printf("\n🔧 Auto-fix attempt %d/%d: asking for a corrected script\n", attempt, c.options.MaxFixAttempts)
+		prompt := fixPrompt(result)
+		result = c.handleTurn(ctx, reader, prompt, c.withLastEdit(prompt))
 	}
+	return result
+}
 
-	return nil
+// handleTurn sends input to the model, then confirms and executes the script it returns.
+// userInput is what the transcript records, while input is what the model receives.
+// It returns the execution result, or nil if nothing was executed.
+func (c *CliAssistant) handleTurn(ctx context.Context, reader *bufio.Reader, userInput, input string) *ExecutionResult {
+	defer c.FlushAudit()
+
+	resp, err := c.processAIResponse(ctx, userInput, input)
+	if interrupted(ctx, err) {
+		fmt.Println("Interrupted.")
+		return nil
+	}
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to process AI response")
+		fmt.Printf("Error: %v\n", err)
+		return nil
+	}
+
+	var script *AssistantResult
+	err = c.parseWithRepair(resp, func(resp string) (err error) {
+		script, err = c.parseScript(resp)
+		return err
+	}, func(attempt int, parseErr error) (string, error) {
+		fmt.Printf("\n🔁 %v\nAsking the AI for a valid response (%d/%d)\n", parseErr, attempt, c.options.MaxRepairAttempts)
+		prompt := repairPrompt(parseErr)
+		return c.processAIResponse(ctx, prompt, prompt)
+	})
+	if interrupted(ctx, err) {
+		fmt.Println("Interrupted.")
+		return nil
+	}
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to parse script")
+		fmt.Printf("\nError: %v\n", err)
+		return nil
+	}
+	c.auditResult(script)
+
+	return c.confirmAndExecute(ctx, reader, script)
+}
+
+// needsFix reports whether auto-fix should ask the model to correct a failed script.
+// Scripts the user interrupted are left alone.
+func (c *CliAssistant) needsFix(result *ExecutionResult) bool {
+	return c.options.AutoFix && result != nil && !result.Success && result.FailureReason != FailureInterrupted
+}
+
+// maxFixStderr bounds how much stderr is sent back to the model when asking for a fix
+const maxFixStderr = 4000
+
+// fixPrompt asks the model to correct a failed script
+func fixPrompt(result *ExecutionResult) string {
+	stderr := strings.TrimSpace(result.Stderr)
+	if len(stderr) > maxFixStderr {
+		stderr = "..." + stderr[len(stderr)-maxFixStderr:]
+	}
+	if stderr == "" {
+		stderr = "(empty)"
+	}
+
+	var b strings.Builder
+	b.WriteString("The script you provided failed. Reply with a corrected script.\n")
+	fmt.Fprintf(&b, "Command:\n%s\n", result.Command)
+	fmt.Fprintf(&b, "Exit code: %d\n", result.ExitCode)
+	if result.FailureReason != "" {
+		fmt.Fprintf(&b, "Failure reason: %s\n", result.FailureReason)
+	}
+	fmt.Fprintf(&b, "Stderr:\n%s", stderr)
+	return b.String()
+}
+
+// RunOnce sends a single request to the model with an empty history and returns the parsed result.
+// It does not read from stdin or write to stdout, which makes it suitable for scripting.
+func (c *CliAssistant) RunOnce(ctx context.Context, llm llms.Model, input string) (*AssistantResult, error) {
+	c.SetModel(llm, memory.NewConversationBuffer())
+	return c.ProcessInput(ctx, input)
+}
+
+// SetModel sets the model and conversation memory used to generate responses
+func (c *CliAssistant) SetModel(llm llms.Model, chatMemory schema.Memory) {
+	c.llm = llm
+	c.chain = &chains.LLMChain{
+		Prompt: lcprompts.NewPromptTemplate(
+			c.LoadPrompt(),
+			[]string{"history", "input"},
+		),
+		LLM:          llm,
+		Memory:       chatMemory,
+		OutputParser: outputparser.NewSimple(),
+		OutputKey:    "text",
+	}
 }
 
 func (c *CliAssistant) printWelcomeInfo() {
 	fmt.Println("Welcome to the AutoCmdr App! Type 'exit' to exit, 'clear' to clear history, or 'help' for more info.")
 }
 
-// ProcessInput processes user input and returns AI response
-func (c *CliAssistant) ProcessInput(_ context.Context, _ string) (*AssistantResult, error) {
-	// This would be implemented for programmatic use
-	return nil, fmt.Errorf("not implemented")
+// ProcessInput processes user input and returns AI response.
+// The model and memory must be set with SetModel first. The exchange is saved to memory.
+func (c *CliAssistant) ProcessInput(ctx context.Context, input string) (*AssistantResult, error) {
+	if c.chain == nil {
+		return nil, fmt.Errorf("model not set, call SetModel first")
+	}
+	ctx = withResultSchema(ctx)
+
+	resp, err := c.generate(ctx, c.withLastEdit(c.withLastExecResult(input)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AI response: %w", err)
+	}
+	c.logger.WithField("response", resp).Debug("Received AI response")
+	c.recordTurn(ctx, input, resp)
+
+	var result *AssistantResult
+	err = c.parseWithRepair(resp, func(resp string) (err error) {
+		result, err = c.parseScript(resp)
+		return err
+	}, func(_ int, parseErr error) (string, error) {
+		prompt := repairPrompt(parseErr)
+		resp, err := c.generate(ctx, prompt)
+		if err != nil {
+			return "", fmt.Errorf("failed to get AI response: %w", err)
+		}
+		c.logger.WithField("response", resp).Debug("Received AI response")
+		c.recordTurn(ctx, prompt, resp)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.auditResult(result)
+	return result, nil
 }
 
-// ExecuteScript executes a script and returns the result
+// ExecuteScript executes a script and returns the result.
+// If a policy is set, scripts it denies are refused with a *policy.DeniedError.
+// The audit log records the execution as approved by the caller.
+// Signals are left to the caller, which stops the script by cancelling ctx.
 func (c *CliAssistant) ExecuteScript(ctx context.Context, script string) (*ExecutionResult, error) {
-	startTime := time.Now()
+	return c.ExecuteApproved(ctx, script, ApprovalCaller)
+}
 
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.CommandContext(ctx, "powershell", "-Command", script)
-	default:
-		cmd = exec.CommandContext(ctx, "bash", "-c", script)
+// ExecuteApproved executes a script like ExecuteScript and records in the audit log how it was approved
+func (c *CliAssistant) ExecuteApproved(ctx context.Context, script string, approval Approval) (*ExecutionResult, error) {
+	if c.policy != nil {
+		if _, err := c.policy.Check(script); err != nil {
+			return nil, err
+		}
+	}
+	if !c.executor.CanExecute(script) {
+		return nil, fmt.Errorf("cannot execute script with shell %s", c.executor.GetShell())
+	}
+
+	result, err := c.execute(ctx, script)
+	if err != nil {
+		return nil, err
 	}
 
-	output, err := cmd.CombinedOutput()
-	duration := time.Since(startTime)
+	c.lastExecResult = result
+	if c.turn != nil {
+		c.turn.Confirmed = approval == ApprovalUser
+		c.turn.Approval = approval
+		c.turn.Execution = result
+		if c.turn.Result != nil && len(c.turn.Result.Steps) > 0 {
+			c.turn.Executions = append(c.turn.Executions, result)
+		}
+	}
+	if c.session != nil {
+		c.session.AddExecution(result)
+		// The execution is saved even when ctx was cancelled to stop the script
+		c.saveSession(context.WithoutCancel(ctx))
+	}
+	return result, nil
+}
 
-	result := &ExecutionResult{
-		Command:  script,
-		Duration: duration.String(),
-		Output:   string(output),
+// execute runs a single-line script inline and a multi-line script from a temporary file
+func (c *CliAssistant) execute(ctx context.Context, script string) (*ExecutionResult, error) {
+	if !isMultiLine(script) {
+		return c.executor.Execute(ctx, script)
 	}
 
+	path, err := writeScriptFile(c.executor, script)
 	if err != nil {
-		result.Success = false
-		result.Error = err.Error()
-		if exitError, ok := err.(*exec.ExitError); ok {
-			result.ExitCode = exitError.ExitCode()
-		} else {
-			result.ExitCode = -1
-		}
-	} else {
-		result.Success = true
-		result.ExitCode = 0
+		return nil, err
 	}
+	defer func() {
+		if err := os.Remove(path); err != nil {
+			c.logger.WithError(err).WithField("path", path).Warn("Failed to remove script file")
+		}
+	}()
+	c.logger.WithField("path", path).Debug("Executing script file")
 
+	result, err := c.executor.ExecuteFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	result.Command = script
 	return result, nil
 }
 
+// saveScript asks for a path and saves the script there
+func (c *CliAssistant) saveScript(reader *bufio.Reader, script string) {
+	fmt.Print("Save to: ")
+	path, err := reader.ReadString('\n')
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to read path")
+		return
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return
+	}
+
+	saved, err := saveScriptFile(c.executor, script, path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved script to %s\n", saved)
+}
+
 // LoadPrompt loads the system prompt
 func (c *CliAssistant) LoadPrompt() string {
 	systemPrompt := c.promptLoader.LoadSystemPrompt()
 	return c.promptLoader.CreateConversationPrompt(systemPrompt)
 }
 
+// SetEnvironment sets the facts about the user's environment included in the system prompt.
+// It must be called before the model is set.
+func (c *CliAssistant) SetEnvironment(environment string) {
+	c.promptLoader.SetEnvironment(environment)
+}
+
+// SetTemplateDirs sets the directories searched for user-defined prompt templates
+func (c *CliAssistant) SetTemplateDirs(dirs ...string) {
+	c.promptLoader.SetTemplateDirs(dirs...)
+}
+
+// SetTemplate selects the system prompt template by name.
+// If the model is already set, the next request uses the new prompt.
+func (c *CliAssistant) SetTemplate(name string) error {
+	if err := c.promptLoader.SetTemplate(name); err != nil {
+		return err
+	}
+	if c.chain != nil {
+		c.chain.Prompt = lcprompts.NewPromptTemplate(c.LoadPrompt(), []string{"history", "input"})
+	}
+	return nil
+}
+
+// SetPolicy sets the policy that scripts are checked against before execution
+func (c *CliAssistant) SetPolicy(p *policy.Policy) {
+	c.policy = p
+}
+
+// SetSession sets the session whose transcript and executions are recorded and saved to store after each turn.
+// The session memory should be passed to Run.
+func (c *CliAssistant) SetSession(session *Session, store SessionStore) {
+	c.session = session
+	c.sessionStore = store
+	c.lastExecResult = session.LastExec
+}
+
+// SetAuditLog sets the log that each turn is recorded to
+func (c *CliAssistant) SetAuditLog(log AuditLog) {
+	c.audit = log
+}
+
+// FlushAudit writes the audit entry of the current turn, if any.
+// Run flushes after each turn; callers of ProcessInput should flush once they have decided
+// whether to execute the script. A pending entry is also flushed when the next turn starts.
+func (c *CliAssistant) FlushAudit() {
+	if c.turn == nil {
+		return
+	}
+	entry := c.turn
+	c.turn = nil
+	if err := c.audit.Append(entry); err != nil {
+		c.logger.WithError(err).Warn("Failed to write audit log")
+	}
+}
+
+// auditResult records the parsed script in the current audit entry
+func (c *CliAssistant) auditResult(result *AssistantResult) {
+	if c.turn != nil {
+		c.turn.Result = result
+	}
+}
+
+// recordTurn adds a user input and the model response to the session transcript and starts a new audit entry
+func (c *CliAssistant) recordTurn(ctx context.Context, input, resp string) {
+	if c.audit != nil {
+		c.FlushAudit()
+		c.turn = &AuditEntry{Time: time.Now(), Prompt: input, Response: resp}
+		if c.session != nil {
+			c.turn.SessionID = c.session.ID
+		}
+	}
+	if c.session == nil {
+		return
+	}
+	c.session.AddMessage(RoleHuman, input)
+	c.session.AddMessage(RoleAI, resp)
+	c.saveSession(ctx)
+}
+
+// saveSession persists the current session, logging failures
+func (c *CliAssistant) saveSession(ctx context.Context) {
+	if c.session == nil || c.sessionStore == nil {
+		return
+	}
+	if err := c.sessionStore.Save(ctx, c.session); err != nil {
+		c.logger.WithError(err).Warn("Failed to save session")
+	}
+}
+
 // SetOptions sets chat options
 func (c *CliAssistant) SetOptions(options *Options) {
 	c.options = options
+	c.promptLoader.SetLanguage(options.Language)
+	if c.chain != nil {
+		c.chain.Prompt = lcprompts.NewPromptTemplate(c.LoadPrompt(), []string{"history", "input"})
+	}
 }
 
 // handleUserInput handles user input with readline support
@@ -186,117 +488,283 @@ func (c *CliAssistant) handleUserInput(ctx context.Context, chatMemory schema.Me
 		c.logger.Info("Exiting the chat...")
 		return "", false
 	case "clear":
-		if err := chatMemory.Clear(ctx); err != nil {
+		clearHistory := chatMemory.Clear
+		if c.session != nil {
+			clearHistory = c.session.Clear
+		}
+		if err := clearHistory(ctx); err != nil {
 			c.logger.WithError(err).Error("Failed to clear memory")
 			return "", false
 		}
+		c.saveSession(ctx)
 		c.logger.Info("Chat history cleared.")
 		return "", true
+	case "autofix":
+		c.options.AutoFix = !c.options.AutoFix
+		if c.options.AutoFix {
+			fmt.Printf("Auto-fix enabled, up to %d attempts per failure.\n", c.options.MaxFixAttempts)
+		} else {
+			fmt.Println("Auto-fix disabled.")
+		}
+		return "", true
+	case "plan":
+		c.options.PlanMode = !c.options.PlanMode
+		if c.options.PlanMode {
+			fmt.Println("Plan mode enabled, requests are answered with a list of steps.")
+		} else {
+			fmt.Println("Plan mode disabled.")
+		}
+		return "", true
+	case "agent":
+		c.options.AgentMode = !c.options.AgentMode
+		if c.options.AgentMode {
+			fmt.Println("Agent mode enabled, the AI can inspect the system with read-only tools before answering.")
+		} else {
+			fmt.Println("Agent mode disabled.")
+		}
+		return "", true
 	case "help":
-		c.logger.Info("Available commands: exit, clear, help")
+		c.logger.Info("Available commands: exit, clear, autofix, plan, agent, /explain <command>, /template [name], help")
 		return "", true
 	default:
+		if command, ok := explainCommand(userInput); ok {
+			c.explainInteractive(ctx, command)
+			return "", true
+		}
+		if name, ok := slashCommand(userInput, "/template"); ok {
+			c.templateInteractive(name)
+			return "", true
+		}
 		return userInput, true
 	}
 }
 
-// processAIResponse processes the AI response with streaming
-func (c *CliAssistant) processAIResponse(ctx context.Context, chain *chains.LLMChain, userInput string) (string, error) {
-	start := false
+// slashCommand reports whether input is the REPL command named by prefix, such as "/template",
+// and returns its argument
+func slashCommand(input, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(input, prefix)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
 
-	// Append last execution result if available
-	if c.lastExecResult != nil {
-		userInput = fmt.Sprintf("Last execution result: %s\n%s", c.lastExecResult.Output, userInput)
+// withLastExecResult prepends the last execution result to the user input if available.
+// After a plan, the outcome of every step is prepended instead, once.
+func (c *CliAssistant) withLastExecResult(userInput string) string {
+	if c.lastPlan != "" {
+		summary := c.lastPlan
+		c.lastPlan = ""
+		return summary + "\n" + userInput
+	}
+	if c.lastExecResult == nil {
+		return userInput
 	}
+	return fmt.Sprintf("Last execution result: %s\n%s", c.lastExecResult.Output, userInput)
+}
 
-	resp, err := chains.Run(ctx, chain, userInput, chains.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
-		if !start {
-			fmt.Print("Bot: ")
-			start = true
+// withLastEdit tells the model how the user corrected its previous script, once
+func (c *CliAssistant) withLastEdit(userInput string) string {
+	if c.lastEdit == nil {
+		return userInput
+	}
+	edit := c.lastEdit
+	c.lastEdit = nil
+	return fmt.Sprintf("The user edited your previous script before running it.\nYour script:\n%s\nScript that was run:\n%s\n%s",
+		edit.Original, edit.Edited, userInput)
+}
+
+// generate sends input to the model through the chain, or through the tool-calling loop in agent mode
+func (c *CliAssistant) generate(ctx context.Context, input string) (string, error) {
+	if c.options.AgentMode {
+		return c.runAgent(ctx, input, nil)
+	}
+	return chains.Run(ctx, c.chain, input)
+}
+
+// processAIResponse sends input to the model and streams the response.
+// In agent mode, the tool calls are shown and the final answer is printed once it is complete.
+// userInput is recorded in the session transcript.
+func (c *CliAssistant) processAIResponse(ctx context.Context, userInput, input string) (string, error) {
+	start := false
+
+	var resp string
+	var err error
+	if c.options.AgentMode {
+		resp, err = c.runAgent(withResultSchema(ctx), input, printToolCall)
+		if err == nil {
+			fmt.Printf("Bot: %s\n", resp)
 		}
-		fmt.Print(string(chunk))
-		return nil
-	}))
+	} else {
+		resp, err = chains.Run(withResultSchema(ctx), c.chain, input, chains.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			if !start {
+				fmt.Print("Bot: ")
+				start = true
+			}
+			fmt.Print(string(chunk))
+			return nil
+		}))
+	}
 
 	if start {
 		fmt.Println() // Add newline after streaming
 	}
+	if err == nil {
+		c.recordTurn(ctx, userInput, resp)
+	}
 
 	return resp, err
 }
 
 // parseScript parses the AI response to extract script information
 func (c *CliAssistant) parseScript(resp string) (*AssistantResult, error) {
-	resp = strings.TrimSpace(resp)
-	thinkEnd := "</think>"
-
-	// Extract content after thinking block
-	if idx := strings.LastIndex(resp, thinkEnd); idx != -1 {
-		resp = resp[idx+len(thinkEnd):]
+	result := &AssistantResult{}
+	if err := c.decodeResponse(resp, resultSchema, result); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	jsonStr, err := utils.ExtractFirstJSON(resp)
+// decodeResponse extracts the JSON object of a model response, validates it against schema and unmarshals it into v.
+// Near-miss JSON, such as an object with trailing commas or single quotes, is repaired locally before giving up.
+func (c *CliAssistant) decodeResponse(resp string, schema *validate.Field, v interface{}) error {
+	jsonStr, err := extractJSON(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract JSON from response: %w", err)
+		repaired, repairs, repairErr := utils.ExtractRepairedJSON(strings.TrimSpace(resp))
+		if repairErr != nil {
+			return err
+		}
+		c.logger.WithField("repairs", strings.Join(repairs, ", ")).Debug("Repaired invalid JSON in the AI response")
+		jsonStr = repaired
 	}
 
-	result := &AssistantResult{}
-	if err := json.Unmarshal([]byte(jsonStr), result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	normalized, err := validate.Normalize([]byte(jsonStr), schema)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(normalized, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
 	}
+	return nil
+}
 
-	return result, nil
+// withResultSchema asks backends that support structured output to answer with an AssistantResult
+func withResultSchema(ctx context.Context) context.Context {
+	return provider.WithResponseSchema(ctx, "assistant_result", resultSchema.JSONSchema())
+}
+
+// extractJSON returns the JSON object of a model response, ignoring any thinking block
+func extractJSON(resp string) (string, error) {
+	jsonStr, err := utils.ExtractFirstJSON(strings.TrimSpace(resp))
+	if err != nil {
+		return "", fmt.Errorf("failed to extract JSON from response: %w", err)
+	}
+	return jsonStr, nil
 }
 
-// confirmAndExecute handles script confirmation and execution
-func (c *CliAssistant) confirmAndExecute(ctx context.Context, reader *bufio.Reader, script *AssistantResult) {
+// confirmAndExecute handles script confirmation and execution, walking through the steps of a plan.
+// It returns the execution result, or nil if no single script was executed.
+func (c *CliAssistant) confirmAndExecute(ctx context.Context, reader *bufio.Reader, script *AssistantResult) *ExecutionResult {
 	if !script.Success {
 		fmt.Printf("\nAI did not provide a script: %s\n", script.Script)
-		return
+		return nil
 	}
-
-	if script.MultipleLines {
-		fmt.Println("\nAI: Please save the following content as a script file and execute:")
-		fmt.Println(script.Script)
-		return
+	if len(script.Steps) > 0 {
+		c.runPlan(ctx, reader, script.Steps)
+		return nil
 	}
+	return c.confirmAndExecuteScript(ctx, reader, script.Script)
+}
 
-	scriptContent := strings.TrimSpace(script.Script)
-	fmt.Println("\nExecute script directly? (y/n)")
-	fmt.Print("You: ")
-
-	confirm, err := reader.ReadString('\n')
-	if err != nil {
-		c.logger.WithError(err).Error("Failed to read confirmation")
-		return
+// confirmAndExecuteScript confirms, optionally edits, and executes a single script.
+// It returns the execution result, or nil if the script was not executed.
+func (c *CliAssistant) confirmAndExecuteScript(ctx context.Context, reader *bufio.Reader, script string) *ExecutionResult {
+	generated := strings.TrimSpace(script)
+	scriptContent := generated
+	if isMultiLine(scriptContent) {
+		fmt.Println("\nScript:")
+		printNumbered(scriptContent)
 	}
 
-	confirm = strings.TrimSpace(confirm)
-	if confirm == "y" || confirm == "Y" {
-		result, err := c.ExecuteScript(ctx, scriptContent)
+	var approval Approval
+	for approval == "" {
+		answer, err := c.confirmScript(reader, scriptContent)
 		if err != nil {
-			c.logger.WithError(err).Error("Failed to execute script")
-			fmt.Printf("Execution error: %v\n", err)
-			return
+			var denied *policy.DeniedError
+			if errors.As(err, &denied) {
+				fmt.Printf("\n🚫 %v\n", denied)
+				return nil
+			}
+			if interrupted(ctx, err) {
+				fmt.Println("Execution cancelled.")
+				return nil
+			}
+			c.logger.WithError(err).Error("Failed to read confirmation")
+			return nil
 		}
 
-		c.lastExecResult = result
-
-		if result.Success {
-			fmt.Printf("✅ Script executed successfully (exit code: %d)\n", result.ExitCode)
-			if result.Output != "" {
-				fmt.Printf("Output:\n%s\n", result.Output)
+		switch answer {
+		case choiceRun:
+			approval = ApprovalUser
+		case choiceAutoRun:
+			approval = ApprovalPolicy
+		case choiceEdit:
+			edited, err := editScript(c.executor, scriptContent)
+			if err != nil {
+				fmt.Printf("Edit failed: %v\n", err)
+				continue
 			}
-		} else {
-			fmt.Printf("❌ Script execution failed (exit code: %d)\n", result.ExitCode)
-			if result.Error != "" {
-				fmt.Printf("Error: %s\n", result.Error)
+			if edited == "" {
+				fmt.Println("Script is empty, execution cancelled.")
+				return nil
 			}
-			if result.Output != "" {
-				fmt.Printf("Output:\n%s\n", result.Output)
+			scriptContent = edited
+			if isMultiLine(scriptContent) {
+				fmt.Println("\nEdited script:")
+				printNumbered(scriptContent)
 			}
+		case choiceSave:
+			c.saveScript(reader, scriptContent)
+		default:
+			fmt.Println("Execution cancelled.")
+			return nil
 		}
+	}
 
-		fmt.Printf("Duration: %s\n", result.Duration)
+	if scriptContent != generated {
+		c.lastEdit = &scriptEdit{Original: generated, Edited: scriptContent}
 	}
+
+	// An interrupt while the script runs cancels only the script
+	execCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	c.executing.Store(true)
+	result, err := c.ExecuteApproved(execCtx, scriptContent, approval)
+	c.executing.Store(false)
+	stop()
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to execute script")
+		fmt.Printf("Execution error: %v\n", err)
+		return nil
+	}
+
+	if result.Success {
+		fmt.Printf("✅ Script executed successfully (exit code: %d)\n", result.ExitCode)
+	} else {
+		fmt.Printf("❌ Script execution failed (exit code: %d)\n", result.ExitCode)
+		if result.FailureReason != "" {
+			fmt.Printf("Reason: %s\n", result.FailureReason)
+		}
+		if result.Error != "" {
+			fmt.Printf("Error: %s\n", result.Error)
+		}
+	}
+	if result.Output != "" && !result.Streamed {
+		fmt.Printf("Output:\n%s\n", result.Output)
+	}
+	if result.Truncated {
+		fmt.Println("Note: output was truncated in the captured result")
+	}
+
+	fmt.Printf("Duration: %s\n", result.Duration)
+	return result
 }
@@ -0,0 +1,276 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmc/langchaingo/llms/fake"
+	"github.com/tmc/langchaingo/memory"
+
+	"github.com/blysin/autocmdr/pkg/policy"
+)
+
+func newTestAssistant() *CliAssistant {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return NewCliAssistant(DefaultChatOptions(), NewShExecutor(), logger)
+}
+
+func TestProcessInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected *AssistantResult
+		wantErr  bool
+	}{
+		{
+			name:     "plain JSON",
+			response: `{"success": true, "multipleLines": false, "script": "ls -la"}`,
+			expected: &AssistantResult{Success: true, Script: "ls -la"},
+		},
+		{
+			name:     "JSON after think block",
+			response: "<think>{\"success\": false}</think>\nHere you go: {\"success\": true, \"multipleLines\": true, \"script\": \"echo a\\necho b\"}",
+			expected: &AssistantResult{Success: true, MultipleLines: true, Script: "echo a\necho b"},
+		},
+		{
+			name:     "braces in script",
+			response: "Use this:\n```json\n{\"success\": true, \"multipleLines\": false, \"script\": \"find . -name '*.log' -exec awk '{print $1}' {} \\\\;\"}\n```",
+			expected: &AssistantResult{Success: true, Script: `find . -name '*.log' -exec awk '{print $1}' {} \;`},
+		},
+		{
+			name:     "string booleans",
+			response: `{"success": "true", "multipleLines": "false", "script": "ls -la"}`,
+			expected: &AssistantResult{Success: true, Script: "ls -la"},
+		},
+		{
+			name:     "near-miss JSON",
+			response: "{\n  'success': true, // ready\n  'multipleLines': true,\n  'script': 'cd /tmp\nls -la',\n}",
+			expected: &AssistantResult{Success: true, MultipleLines: true, Script: "cd /tmp\nls -la"},
+		},
+		{
+			name:     "plan steps",
+			response: `{"success": true, "steps": [{"description": "create dir", "script": "mkdir out", "expected": "out exists"}, {"description": "list", "script": "ls out"}]}`,
+			expected: &AssistantResult{Success: true, Steps: []PlanStep{
+				{Description: "create dir", Script: "mkdir out", Expected: "out exists"},
+				{Description: "list", Script: "ls out"},
+			}},
+		},
+		{
+			name:     "no JSON",
+			response: "I cannot help with that.",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assistant := newTestAssistant()
+			assistant.SetModel(fake.NewFakeLLM([]string{tt.response}), memory.NewConversationBuffer())
+
+			result, err := assistant.ProcessInput(context.Background(), "list files")
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %+v but got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestProcessInputWithoutModel(t *testing.T) {
+	assistant := newTestAssistant()
+	if _, err := assistant.ProcessInput(context.Background(), "list files"); err == nil {
+		t.Error("expected error but got none")
+	}
+}
+
+func TestProcessInputSavesHistory(t *testing.T) {
+	assistant := newTestAssistant()
+	chatMemory := memory.NewConversationBuffer()
+	assistant.SetModel(fake.NewFakeLLM([]string{`{"success": true, "multipleLines": false, "script": "pwd"}`}), chatMemory)
+
+	if _, err := assistant.ProcessInput(context.Background(), "where am I"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages, err := chatMemory.ChatHistory.Messages(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Errorf("expected 2 messages in history but got %d", len(messages))
+	}
+}
+
+// memoryAuditLog collects audit entries in memory
+type memoryAuditLog struct {
+	entries []*AuditEntry
+}
+
+func (l *memoryAuditLog) Append(entry *AuditEntry) error {
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func TestAuditRecordsTurn(t *testing.T) {
+	ctx := context.Background()
+	log := &memoryAuditLog{}
+	assistant := newTestAssistant()
+	assistant.SetAuditLog(log)
+	assistant.SetModel(fake.NewFakeLLM([]string{
+		`{"success": true, "multipleLines": false, "script": "echo audited"}`,
+		`{"success": true, "multipleLines": false, "script": "echo skipped"}`,
+	}), memory.NewConversationBuffer())
+
+	if _, err := assistant.ProcessInput(ctx, "say audited"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(log.entries) != 0 {
+		t.Fatalf("expected no entry before the turn ends but got %d", len(log.entries))
+	}
+	if _, err := assistant.ExecuteScript(ctx, "echo audited"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Starting the next turn flushes the previous one
+	if _, err := assistant.ProcessInput(ctx, "say skipped"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assistant.FlushAudit()
+	assistant.FlushAudit()
+
+	if len(log.entries) != 2 {
+		t.Fatalf("expected 2 entries but got %d", len(log.entries))
+	}
+
+	executed := log.entries[0]
+	if executed.Prompt != "say audited" || executed.Result == nil || executed.Result.Script != "echo audited" {
+		t.Errorf("unexpected executed entry: %+v", executed)
+	}
+	if executed.Confirmed || executed.Approval != ApprovalCaller {
+		t.Errorf("expected executed entry to be approved by the caller without confirmation: %+v", executed)
+	}
+	if executed.Execution == nil || strings.TrimSpace(executed.Execution.Stdout) != "audited" {
+		t.Errorf("expected executed entry to hold the execution result: %+v", executed)
+	}
+	if executed.Time.IsZero() || executed.Response == "" {
+		t.Errorf("expected time and raw response to be recorded: %+v", executed)
+	}
+
+	skipped := log.entries[1]
+	if skipped.Confirmed || skipped.Execution != nil {
+		t.Errorf("expected unexecuted entry to be unconfirmed: %+v", skipped)
+	}
+}
+
+func TestAuditRecordsApproval(t *testing.T) {
+	allowEcho := policy.New()
+	allowEcho.Allow = []policy.Rule{{Command: "echo"}}
+
+	tests := []struct {
+		name          string
+		policy        *policy.Policy
+		input         string
+		wantApproval  Approval
+		wantConfirmed bool
+	}{
+		{name: "confirmed by the user", input: "y\n", wantApproval: ApprovalUser, wantConfirmed: true},
+		{name: "auto-approved by the policy", policy: allowEcho, wantApproval: ApprovalPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assistant := newTestAssistant()
+			assistant.SetPolicy(tt.policy)
+			assistant.turn = &AuditEntry{}
+
+			reader := bufio.NewReader(strings.NewReader(tt.input))
+			if result := assistant.confirmAndExecuteScript(context.Background(), reader, "echo approved"); result == nil {
+				t.Fatal("expected the script to run")
+			}
+			if assistant.turn.Approval != tt.wantApproval || assistant.turn.Confirmed != tt.wantConfirmed {
+				t.Errorf("expected approval %q and confirmed %v but got %+v", tt.wantApproval, tt.wantConfirmed, assistant.turn)
+			}
+		})
+	}
+}
+
+func TestWithLastEdit(t *testing.T) {
+	assistant := newTestAssistant()
+	if got := assistant.withLastEdit("next"); got != "next" {
+		t.Errorf("expected input unchanged without an edit but got %q", got)
+	}
+
+	assistant.lastEdit = &scriptEdit{Original: "ls -l /var/log", Edited: "ls -lh /var/log"}
+	got := assistant.withLastEdit("next")
+	for _, want := range []string{"edited your previous script", "ls -l /var/log", "ls -lh /var/log", "next"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in input but got %q", want, got)
+		}
+	}
+	if !strings.HasSuffix(got, "next") {
+		t.Errorf("expected the user input last but got %q", got)
+	}
+
+	if got := assistant.withLastEdit("again"); got != "again" {
+		t.Errorf("expected the edit to be reported only once but got %q", got)
+	}
+}
+
+func TestFixPrompt(t *testing.T) {
+	result := &ExecutionResult{
+		Command:  "ls /missing",
+		ExitCode: 2,
+		Stderr:   "ls: cannot access '/missing': No such file or directory\n",
+	}
+	prompt := fixPrompt(result)
+	for _, want := range []string{"corrected script", "ls /missing", "Exit code: 2", "No such file or directory"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected %q in prompt but got %q", want, prompt)
+		}
+	}
+
+	long := &ExecutionResult{Command: "x", ExitCode: 1, Stderr: strings.Repeat("a", maxFixStderr) + "tail"}
+	if prompt := fixPrompt(long); !strings.HasSuffix(prompt, "tail") || len(prompt) > maxFixStderr+200 {
+		t.Errorf("expected stderr to be cut to its end, got %d bytes", len(prompt))
+	}
+}
+
+func TestNeedsFix(t *testing.T) {
+	tests := []struct {
+		name     string
+		autoFix  bool
+		result   *ExecutionResult
+		expected bool
+	}{
+		{name: "disabled", autoFix: false, result: &ExecutionResult{ExitCode: 1}, expected: false},
+		{name: "not executed", autoFix: true, result: nil, expected: false},
+		{name: "succeeded", autoFix: true, result: &ExecutionResult{Success: true}, expected: false},
+		{name: "failed", autoFix: true, result: &ExecutionResult{ExitCode: 1}, expected: true},
+		{name: "timed out", autoFix: true, result: &ExecutionResult{ExitCode: -1, FailureReason: FailureTimeout}, expected: true},
+		{name: "interrupted", autoFix: true, result: &ExecutionResult{ExitCode: -1, FailureReason: FailureInterrupted}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assistant := newTestAssistant()
+			assistant.options.AutoFix = tt.autoFix
+			if got := assistant.needsFix(tt.result); got != tt.expected {
+				t.Errorf("expected %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"bytes"
+	"sync"
+)
+
+// cappedBuffer is a goroutine-safe buffer that keeps at most limit bytes and drops the rest.
+// Writes always succeed so that a command is never blocked by a full buffer.
+type cappedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// newCappedBuffer creates a buffer that keeps at most limit bytes; zero or less means no limit
+func newCappedBuffer(limit int) *cappedBuffer {
+	return &cappedBuffer{limit: limit}
+}
+
+// Write implements io.Writer
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(p)
+	if b.limit > 0 {
+		remaining := b.limit - b.buf.Len()
+		if remaining <= 0 {
+			b.truncated = n > 0 || b.truncated
+			return n, nil
+		}
+		if len(p) > remaining {
+			p = p[:remaining]
+			b.truncated = true
+		}
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// String returns the captured content
+func (b *cappedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Truncated reports whether output was dropped because the limit was reached
+func (b *cappedBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated
+}
@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/memory"
+
+	"github.com/blysin/autocmdr/pkg/config"
+	"github.com/blysin/autocmdr/pkg/llmtest"
+	"github.com/blysin/autocmdr/pkg/provider"
+)
+
+// openCassette replays testdata/cassettes/<name>.yaml, or records it with the configured model
+// when AUTOCMDR_RECORD is set
+func openCassette(t *testing.T, name string) *llmtest.Model {
+	return llmtest.Open(t, filepath.Join("testdata", "cassettes", name+".yaml"), nil, func() (llms.Model, error) {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		return provider.New(cfg)
+	})
+}
+
+// newCassetteAssistant creates an assistant whose system prompt is the same on every machine,
+// so that its requests match the recorded ones
+func newCassetteAssistant(t *testing.T, options *Options) *CliAssistant {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	assistant := NewCliAssistant(options, NewShExecutor(), logger)
+	assistant.SetTemplateDirs(filepath.Join("testdata", "prompts"))
+	if err := assistant.SetTemplate("cassette"); err != nil {
+		t.Fatal(err)
+	}
+	return assistant
+}
+
+func TestCassetteProcessInput(t *testing.T) {
+	tests := []struct {
+		cassette string
+		input    string
+		expected *AssistantResult
+	}{
+		{
+			cassette: "list_files",
+			input:    "list all files in this directory, including hidden ones",
+			expected: &AssistantResult{Success: true, Script: "ls -la"},
+		},
+		{
+			cassette: "repair_response",
+			input:    "show the disk usage of this directory",
+			expected: &AssistantResult{Success: true, Script: "du -sh ."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cassette, func(t *testing.T) {
+			assistant := newCassetteAssistant(t, DefaultChatOptions())
+			assistant.SetModel(openCassette(t, tt.cassette), memory.NewConversationBuffer())
+
+			result, err := assistant.ProcessInput(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("ProcessInput() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ProcessInput() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCassetteAutoFix(t *testing.T) {
+	options := DefaultChatOptions()
+	options.AutoFix = true
+	options.MaxFixAttempts = 2
+	assistant := newCassetteAssistant(t, options)
+	chatMemory := memory.NewConversationBuffer()
+	assistant.SetModel(openCassette(t, "auto_fix"), chatMemory)
+
+	// Confirm the failing script and its correction
+	reader := bufio.NewReader(strings.NewReader("y\ny\n"))
+	result := assistant.handleInput(context.Background(), reader, "check whether config.yaml exists here")
+
+	if result == nil || !result.Success {
+		t.Fatalf("handleInput() = %+v, want the corrected script to succeed", result)
+	}
+	if !strings.Contains(result.Output, "config.yaml not found") {
+		t.Errorf("handleInput() output = %q, want the corrected script's output", result.Output)
+	}
+	messages, err := chatMemory.ChatHistory.Messages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 4 || !strings.HasPrefix(messages[2].GetContent(), "The script you provided failed.") {
+		t.Errorf("history = %+v, want the request, the failure and both answers", messages)
+	}
+}
@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/blysin/autocmdr/pkg/policy"
+	"github.com/blysin/autocmdr/pkg/risk"
+)
+
+// choice is the user's answer at the confirmation prompt
+type choice int
+
+const (
+	choiceCancel choice = iota
+	choiceRun
+	// choiceAutoRun runs a script the policy approved without asking
+	choiceAutoRun
+	choiceEdit
+	choiceSave
+)
+
+// confirmScript asks the user to confirm a script, with a prompt that scales with its risk.
+// The script can also be edited, and multi-line scripts can be saved to a file.
+// Scripts denied by the policy return a *policy.DeniedError; allow-listed read-only scripts are confirmed without asking.
+func (c *CliAssistant) confirmScript(reader *bufio.Reader, script string) (choice, error) {
+	assessment := risk.Analyze(script)
+	c.logger.WithFields(logrus.Fields{
+		"level":    assessment.Level.String(),
+		"findings": len(assessment.Findings),
+	}).Debug("Script risk assessed")
+
+	if c.policy != nil {
+		decision := c.policy.Evaluate(assessment)
+		switch decision.Action {
+		case policy.Deny:
+			return choiceCancel, &policy.DeniedError{Rule: *decision.Rule, Command: decision.Command}
+		case policy.AutoApprove:
+			fmt.Println("\nAllowed by policy, executing without confirmation")
+			return choiceAutoRun, nil
+		}
+	}
+
+	printAssessment(assessment)
+
+	multiLine := isMultiLine(script)
+	extra := ", e to edit"
+	if multiLine {
+		extra += ", s to save"
+	}
+	switch assessment.Level {
+	case risk.ReadOnly, risk.Mutating:
+		fmt.Printf("\nExecute script directly? (y/n%s)\n", extra)
+	default:
+		fmt.Printf("\nType %q to execute%s, or anything else to cancel\n", assessment.FirstWord, extra)
+	}
+	fmt.Print("You: ")
+
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		return choiceCancel, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	answer = strings.TrimSpace(answer)
+
+	confirmed := false
+	switch assessment.Level {
+	case risk.ReadOnly, risk.Mutating:
+		confirmed = strings.EqualFold(answer, "y")
+	default:
+		confirmed = answer != "" && answer == assessment.FirstWord
+	}
+
+	switch {
+	case confirmed:
+		return choiceRun, nil
+	case strings.EqualFold(answer, "e"):
+		return choiceEdit, nil
+	case multiLine && strings.EqualFold(answer, "s"):
+		return choiceSave, nil
+	default:
+		return choiceCancel, nil
+	}
+}
+
+// printAssessment prints the risk level of a script and the reasons behind it
+func printAssessment(assessment *risk.Assessment) {
+	switch assessment.Level {
+	case risk.ReadOnly:
+		fmt.Printf("\nRisk: %s\n", assessment.Level)
+		return
+	case risk.Mutating:
+		fmt.Printf("\nRisk: %s\n", assessment.Level)
+	case risk.Destructive:
+		fmt.Printf("\n⚠️  Risk: %s, this script can permanently destroy data\n", assessment.Level)
+	case risk.Privileged:
+		fmt.Printf("\n⚠️  Risk: %s, this script runs with elevated privileges\n", assessment.Level)
+	}
+	printFindings(assessment)
+}
+
+// printFindings prints the reasons that raised the risk level above read-only
+func printFindings(assessment *risk.Assessment) {
+	for _, finding := range assessment.Findings {
+		if finding.Level == risk.ReadOnly {
+			continue
+		}
+		if finding.Command != "" {
+			fmt.Printf("  - %s: %s\n", finding.Command, finding.Reason)
+		} else {
+			fmt.Printf("  - %s\n", finding.Reason)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Supported shell names
+const (
+	ShellBash       = "bash"
+	ShellSh         = "sh"
+	ShellZsh        = "zsh"
+	ShellFish       = "fish"
+	ShellPwsh       = "pwsh"
+	ShellPowershell = "powershell"
+)
+
+// DefaultMaxOutputBytes is the default limit on captured output per stream
+const DefaultMaxOutputBytes = 1 << 20
+
+// waitDelay bounds how long Execute waits for output after a script is killed,
+// in case a background child still holds the output pipes open
+const waitDelay = 2 * time.Second
+
+// ExecOptions controls how scripts are executed
+type ExecOptions struct {
+	// Stdout and Stderr receive output live while the script runs; nil disables streaming
+	Stdout io.Writer
+	Stderr io.Writer
+	// MaxOutputBytes caps the output captured in ExecutionResult; zero or less means no limit
+	MaxOutputBytes int
+	// Timeout stops a script after this wall-clock duration; zero means no timeout
+	Timeout time.Duration
+	// Limits are resource limits applied to the script on Linux
+	Limits ResourceLimits
+	// Dir is the working directory of scripts; empty uses the current directory
+	Dir string
+}
+
+// DefaultExecOptions returns default execution options, capturing output without streaming it
+func DefaultExecOptions() *ExecOptions {
+	return &ExecOptions{
+		MaxOutputBytes: DefaultMaxOutputBytes,
+	}
+}
+
+// ShellExecutor implements ScriptExecutor by running scripts through a shell binary
+type ShellExecutor struct {
+	shell    string
+	binary   string
+	args     []string
+	fileArgs []string
+	options  *ExecOptions
+}
+
+// NewBashExecutor creates an executor that runs scripts with bash
+func NewBashExecutor() *ShellExecutor {
+	return &ShellExecutor{shell: ShellBash, binary: "bash", args: []string{"-c"}, fileArgs: nil, options: DefaultExecOptions()}
+}
+
+// NewShExecutor creates an executor that runs scripts with the POSIX sh
+func NewShExecutor() *ShellExecutor {
+	return &ShellExecutor{shell: ShellSh, binary: "sh", args: []string{"-c"}, fileArgs: nil, options: DefaultExecOptions()}
+}
+
+// NewZshExecutor creates an executor that runs scripts with zsh
+func NewZshExecutor() *ShellExecutor {
+	return &ShellExecutor{shell: ShellZsh, binary: "zsh", args: []string{"-c"}, fileArgs: nil, options: DefaultExecOptions()}
+}
+
+// NewFishExecutor creates an executor that runs scripts with fish
+func NewFishExecutor() *ShellExecutor {
+	return &ShellExecutor{shell: ShellFish, binary: "fish", args: []string{"-c"}, fileArgs: nil, options: DefaultExecOptions()}
+}
+
+// NewPwshExecutor creates an executor that runs scripts with PowerShell Core
+func NewPwshExecutor() *ShellExecutor {
+	return &ShellExecutor{shell: ShellPwsh, binary: "pwsh", args: []string{"-NoProfile", "-NonInteractive", "-Command"}, fileArgs: []string{"-NoProfile", "-NonInteractive", "-File"}, options: DefaultExecOptions()}
+}
+
+// NewPowershellExecutor creates an executor that runs scripts with Windows PowerShell
+func NewPowershellExecutor() *ShellExecutor {
+	return &ShellExecutor{shell: ShellPowershell, binary: "powershell", args: []string{"-NoProfile", "-NonInteractive", "-Command"}, fileArgs: []string{"-NoProfile", "-NonInteractive", "-File"}, options: DefaultExecOptions()}
+}
+
+// NewScriptExecutor creates an executor for the given shell name.
+// An empty name selects the shell detected from the environment, and nil options select the defaults.
+func NewScriptExecutor(shell string, options *ExecOptions) (ScriptExecutor, error) {
+	if shell == "" {
+		shell = DetectShell()
+	}
+
+	var executor *ShellExecutor
+	switch strings.ToLower(shell) {
+	case ShellBash:
+		executor = NewBashExecutor()
+	case ShellSh:
+		executor = NewShExecutor()
+	case ShellZsh:
+		executor = NewZshExecutor()
+	case ShellFish:
+		executor = NewFishExecutor()
+	case ShellPwsh:
+		executor = NewPwshExecutor()
+	case ShellPowershell:
+		executor = NewPowershellExecutor()
+	default:
+		return nil, fmt.Errorf("unsupported shell: %s", shell)
+	}
+
+	if options != nil {
+		executor.SetExecOptions(options)
+	}
+	return executor, nil
+}
+
+// SetExecOptions sets the execution options
+func (e *ShellExecutor) SetExecOptions(options *ExecOptions) {
+	e.options = options
+}
+
+// DetectShell returns the shell name derived from $SHELL, falling back to the platform default
+func DetectShell() string {
+	if shell := shellFromPath(os.Getenv("SHELL")); shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		return ShellPowershell
+	}
+	return ShellBash
+}
+
+// shellFromPath maps a shell binary path such as /usr/bin/zsh to a supported shell name
+func shellFromPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(path)), ".exe")
+	switch name {
+	case ShellBash, ShellSh, ShellZsh, ShellFish, ShellPwsh, ShellPowershell:
+		return name
+	default:
+		return ""
+	}
+}
+
+// Execute executes a script command.
+// Output is streamed to the configured writers while it is captured, and the script is
+// stopped when ctx is cancelled or the configured timeout expires.
+func (e *ShellExecutor) Execute(ctx context.Context, command string) (*ExecutionResult, error) {
+	script, err := limitScript(e.shell, e.options.Limits, command)
+	if err != nil {
+		return nil, err
+	}
+	return e.run(ctx, command, append(append([]string{}, e.args...), script))
+}
+
+// ExecuteFile executes a script file written with ScriptExtension and Shebang
+func (e *ShellExecutor) ExecuteFile(ctx context.Context, path string) (*ExecutionResult, error) {
+	if e.options.Limits.IsZero() {
+		return e.run(ctx, path, append(append([]string{}, e.fileArgs...), path))
+	}
+
+	// Limits are applied by the inline prefix, which then replaces itself with the script
+	script, err := limitScript(e.shell, e.options.Limits, "exec "+e.binary+" "+quotePath(e.shell, path))
+	if err != nil {
+		return nil, err
+	}
+	return e.run(ctx, path, append(append([]string{}, e.args...), script))
+}
+
+// ScriptExtension returns the file extension for scripts run by this shell
+func (e *ShellExecutor) ScriptExtension() string {
+	switch e.shell {
+	case ShellFish:
+		return ".fish"
+	case ShellPwsh, ShellPowershell:
+		return ".ps1"
+	default:
+		return ".sh"
+	}
+}
+
+// Shebang returns the interpreter line for script files, or an empty string if the shell does not use one
+func (e *ShellExecutor) Shebang() string {
+	switch e.shell {
+	case ShellSh:
+		return "#!/bin/sh"
+	case ShellPowershell:
+		return ""
+	default:
+		return "#!/usr/bin/env " + e.binary
+	}
+}
+
+// run starts the shell with args and collects the result, recording command as what was run
+func (e *ShellExecutor) run(ctx context.Context, command string, args []string) (*ExecutionResult, error) {
+	runCtx := ctx
+	if e.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, e.options.Timeout)
+		defer cancel()
+	}
+
+	startTime := time.Now()
+
+	cmd := exec.CommandContext(runCtx, e.binary, args...)
+	cmd.WaitDelay = waitDelay
+	cmd.Dir = e.options.Dir
+
+	combined := newCappedBuffer(e.options.MaxOutputBytes)
+	stdout := newCappedBuffer(e.options.MaxOutputBytes)
+	stderr := newCappedBuffer(e.options.MaxOutputBytes)
+	cmd.Stdout = teeWriter(e.options.Stdout, stdout, combined)
+	cmd.Stderr = teeWriter(e.options.Stderr, stderr, combined)
+
+	release := processGroup(cmd)
+	err := cmd.Run()
+	release()
+	duration := time.Since(startTime)
+
+	result := &ExecutionResult{
+		Command:   command,
+		Duration:  duration.String(),
+		Output:    combined.String(),
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: combined.Truncated() || stdout.Truncated() || stderr.Truncated(),
+		Streamed:  e.options.Stdout != nil,
+	}
+
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+		if exitError, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitError.ExitCode()
+		} else {
+			result.ExitCode = -1
+		}
+		result.FailureReason = failureReason(ctx, runCtx, err, e.options.Limits, result.Stderr)
+	} else {
+		result.Success = true
+		result.ExitCode = 0
+	}
+
+	return result, nil
+}
+
+// quotePath quotes a path as a single word for the given shell
+func quotePath(shell, path string) string {
+	if shell == ShellFish {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(path) + "'"
+	}
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// teeWriter writes to the live writer, if any, and to each capture buffer
+func teeWriter(live io.Writer, buffers ...io.Writer) io.Writer {
+	if live == nil {
+		return io.MultiWriter(buffers...)
+	}
+	return io.MultiWriter(append([]io.Writer{live}, buffers...)...)
+}
+
+// CanExecute checks if a command can be executed
+func (e *ShellExecutor) CanExecute(command string) bool {
+	if strings.TrimSpace(command) == "" {
+		return false
+	}
+	_, err := exec.LookPath(e.binary)
+	return err == nil
+}
+
+// GetShell returns the shell type (powershell, bash, etc.)
+func (e *ShellExecutor) GetShell() string {
+	return e.shell
+}
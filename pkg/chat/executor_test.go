@@ -0,0 +1,201 @@
+package chat
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNewScriptExecutor(t *testing.T) {
+	tests := []struct {
+		name     string
+		shell    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "bash", shell: "bash", expected: ShellBash},
+		{name: "sh", shell: "sh", expected: ShellSh},
+		{name: "zsh", shell: "zsh", expected: ShellZsh},
+		{name: "fish", shell: "fish", expected: ShellFish},
+		{name: "pwsh", shell: "pwsh", expected: ShellPwsh},
+		{name: "case insensitive", shell: "ZSH", expected: ShellZsh},
+		{name: "unsupported shell", shell: "tcsh", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, err := NewScriptExecutor(tt.shell, nil)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if executor.GetShell() != tt.expected {
+				t.Errorf("expected shell %q but got %q", tt.expected, executor.GetShell())
+			}
+		})
+	}
+}
+
+func TestShellFromPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/bin/bash", expected: ShellBash},
+		{path: "/usr/bin/zsh", expected: ShellZsh},
+		{path: "/usr/local/bin/fish", expected: ShellFish},
+		{path: "/usr/bin/pwsh", expected: ShellPwsh},
+		{path: "/bin/tcsh", expected: ""},
+		{path: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := shellFromPath(tt.path); got != tt.expected {
+				t.Errorf("expected %q but got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDetectShell(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/fish")
+	if got := DetectShell(); got != ShellFish {
+		t.Errorf("expected %q but got %q", ShellFish, got)
+	}
+
+	t.Setenv("SHELL", "")
+	expected := ShellBash
+	if runtime.GOOS == "windows" {
+		expected = ShellPowershell
+	}
+	if got := DetectShell(); got != expected {
+		t.Errorf("expected %q but got %q", expected, got)
+	}
+}
+
+func TestShellExecutorExecute(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available on windows")
+	}
+
+	executor := NewShExecutor()
+	if !executor.CanExecute("echo hello") {
+		t.Skip("sh is not available")
+	}
+
+	result, err := executor.Execute(context.Background(), "echo hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Success || result.ExitCode != 0 {
+		t.Errorf("expected success but got exit code %d", result.ExitCode)
+	}
+	if strings.TrimSpace(result.Output) != "hello" {
+		t.Errorf("expected output %q but got %q", "hello", result.Output)
+	}
+
+	result, err = executor.Execute(context.Background(), "exit 3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Success || result.ExitCode != 3 {
+		t.Errorf("expected exit code 3 but got %d", result.ExitCode)
+	}
+}
+
+func TestShellExecutorStreamsAndSeparatesOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available on windows")
+	}
+
+	var liveOut, liveErr strings.Builder
+	executor := NewShExecutor()
+	executor.SetExecOptions(&ExecOptions{Stdout: &liveOut, Stderr: &liveErr, MaxOutputBytes: DefaultMaxOutputBytes})
+	if !executor.CanExecute("echo out") {
+		t.Skip("sh is not available")
+	}
+
+	result, err := executor.Execute(context.Background(), "echo out; echo err >&2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Stdout != "out\n" || result.Stderr != "err\n" {
+		t.Errorf("expected separate stdout and stderr but got %q and %q", result.Stdout, result.Stderr)
+	}
+	if !strings.Contains(result.Output, "out") || !strings.Contains(result.Output, "err") {
+		t.Errorf("expected combined output to contain both streams but got %q", result.Output)
+	}
+	if liveOut.String() != "out\n" || liveErr.String() != "err\n" {
+		t.Errorf("expected live output %q and %q but got %q and %q", "out\n", "err\n", liveOut.String(), liveErr.String())
+	}
+	if !result.Streamed {
+		t.Error("expected result to be marked as streamed")
+	}
+}
+
+func TestShellExecutorCapsOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available on windows")
+	}
+
+	executor := NewShExecutor()
+	executor.SetExecOptions(&ExecOptions{MaxOutputBytes: 4})
+	if !executor.CanExecute("echo 0123456789") {
+		t.Skip("sh is not available")
+	}
+
+	result, err := executor.Execute(context.Background(), "echo 0123456789")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("expected success but got %q", result.Error)
+	}
+	if result.Stdout != "0123" || !result.Truncated {
+		t.Errorf("expected truncated output %q but got %q (truncated=%v)", "0123", result.Stdout, result.Truncated)
+	}
+	if result.Streamed {
+		t.Error("expected result not to be marked as streamed")
+	}
+}
+
+func TestCappedBuffer(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		writes    []string
+		expected  string
+		truncated bool
+	}{
+		{name: "under limit", limit: 10, writes: []string{"abc", "def"}, expected: "abcdef"},
+		{name: "exact limit", limit: 6, writes: []string{"abc", "def"}, expected: "abcdef"},
+		{name: "over limit", limit: 4, writes: []string{"abc", "def"}, expected: "abcd", truncated: true},
+		{name: "no limit", limit: 0, writes: []string{"abc", "def"}, expected: "abcdef"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := newCappedBuffer(tt.limit)
+			for _, w := range tt.writes {
+				n, err := buf.Write([]byte(w))
+				if err != nil || n != len(w) {
+					t.Fatalf("expected write of %d bytes but got %d, %v", len(w), n, err)
+				}
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q but got %q", tt.expected, buf.String())
+			}
+			if buf.Truncated() != tt.truncated {
+				t.Errorf("expected truncated=%v but got %v", tt.truncated, buf.Truncated())
+			}
+		})
+	}
+}
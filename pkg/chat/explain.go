@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/blysin/autocmdr/pkg/provider"
+	"github.com/blysin/autocmdr/pkg/risk"
+)
+
+// explainPrefix starts the REPL command that explains a command instead of generating one
+const explainPrefix = "/explain"
+
+// Explain asks the model for a breakdown of what a command does, without running it.
+// The model must be set with SetModel first. The exchange is not saved to memory.
+func (c *CliAssistant) Explain(ctx context.Context, command string) (*Explanation, error) {
+	if c.llm == nil {
+		return nil, fmt.Errorf("model not set, call SetModel first")
+	}
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, fmt.Errorf("no command to explain")
+	}
+	ctx = provider.WithResponseSchema(ctx, "explanation", explanationSchema.JSONSchema())
+
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, c.promptLoader.LoadExplainPrompt()),
+		llms.TextParts(llms.ChatMessageTypeHuman, command),
+	}
+	generate := func() (string, error) {
+		resp, err := c.llm.GenerateContent(ctx, messages)
+		if err != nil {
+			return "", fmt.Errorf("failed to get AI response: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("failed to get AI response: no choices returned")
+		}
+		content := resp.Choices[0].Content
+		c.logger.WithField("response", content).Debug("Received AI explanation")
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeAI, content))
+		return content, nil
+	}
+
+	resp, err := generate()
+	if err != nil {
+		return nil, err
+	}
+
+	var explanation *Explanation
+	err = c.parseWithRepair(resp, func(resp string) error {
+		explanation = &Explanation{}
+		return c.decodeResponse(resp, explanationSchema, explanation)
+	}, func(_ int, parseErr error) (string, error) {
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, repairPrompt(parseErr)))
+		return generate()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return explanation, nil
+}
+
+// explainCommand reports whether input is the /explain command and returns the command to explain
+func explainCommand(input string) (string, bool) {
+	return slashCommand(input, explainPrefix)
+}
+
+// explainInteractive explains a command in the REPL.
+// Without a command, the last executed script is explained.
+func (c *CliAssistant) explainInteractive(ctx context.Context, command string) {
+	if command == "" {
+		if c.lastExecResult == nil {
+			fmt.Println("Usage: /explain <command>")
+			return
+		}
+		command = c.lastExecResult.Command
+	}
+
+	fmt.Println("\nExplaining...")
+	explanation, err := c.Explain(ctx, command)
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to explain command")
+		return
+	}
+	PrintExplanation(command, explanation)
+}
+
+// PrintExplanation prints an explanation of command, followed by the local risk analysis
+func PrintExplanation(command string, explanation *Explanation) {
+	fmt.Println()
+	if isMultiLine(command) {
+		fmt.Println("Command:")
+		printNumbered(command)
+	} else {
+		fmt.Printf("Command: %s\n", strings.TrimSpace(command))
+	}
+	if explanation.Summary != "" {
+		fmt.Printf("\n%s\n", explanation.Summary)
+	}
+
+	if len(explanation.Parts) > 0 {
+		fmt.Println("\nBreakdown:")
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, part := range explanation.Parts {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", part.Text, part.Kind, part.Description)
+		}
+		_ = tw.Flush()
+	}
+
+	if len(explanation.SideEffects) > 0 {
+		fmt.Println("\nSide effects:")
+		for _, effect := range explanation.SideEffects {
+			fmt.Printf("  - %s\n", effect)
+		}
+	}
+
+	if explanation.Risk != "" {
+		fmt.Printf("\nRisk according to the model: %s\n", explanation.Risk)
+		if explanation.RiskReason != "" {
+			fmt.Printf("  %s\n", explanation.RiskReason)
+		}
+	}
+
+	assessment := risk.Analyze(command)
+	fmt.Printf("Risk according to autocmdr: %s\n", assessment.Level)
+	printFindings(assessment)
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms/fake"
+	"github.com/tmc/langchaingo/memory"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected *Explanation
+		wantErr  bool
+	}{
+		{
+			name: "structured breakdown",
+			response: `<think>{"summary": "draft"}</think>
+{"summary": "Counts lines in Go files", "parts": [
+  {"text": "find . -name '*.go'", "kind": "program", "description": "lists Go files"},
+  {"text": "|", "kind": "pipe", "description": "passes the file names on"},
+  {"text": "xargs wc -l", "kind": "program", "description": "counts lines"}
+], "sideEffects": [], "risk": "read-only", "riskReason": "only reads files"}`,
+			expected: &Explanation{
+				Summary: "Counts lines in Go files",
+				Parts: []ExplanationPart{
+					{Text: "find . -name '*.go'", Kind: "program", Description: "lists Go files"},
+					{Text: "|", Kind: "pipe", Description: "passes the file names on"},
+					{Text: "xargs wc -l", Kind: "program", Description: "counts lines"},
+				},
+				SideEffects: []string{},
+				Risk:        "read-only",
+				RiskReason:  "only reads files",
+			},
+		},
+		{
+			name:     "no JSON",
+			response: "This command lists files.",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assistant := newTestAssistant()
+			chatMemory := memory.NewConversationBuffer()
+			assistant.SetModel(fake.NewFakeLLM([]string{tt.response}), chatMemory)
+
+			explanation, err := assistant.Explain(context.Background(), "find . -name '*.go' | xargs wc -l")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(explanation, tt.expected) {
+				t.Errorf("expected %+v but got %+v", tt.expected, explanation)
+			}
+
+			messages, err := chatMemory.ChatHistory.Messages(context.Background())
+			if err != nil {
+				t.Fatalf("failed to read history: %v", err)
+			}
+			if len(messages) != 0 {
+				t.Errorf("expected explanations to stay out of the history, got %d messages", len(messages))
+			}
+		})
+	}
+}
+
+func TestExplainErrors(t *testing.T) {
+	assistant := newTestAssistant()
+	if _, err := assistant.Explain(context.Background(), "ls"); err == nil {
+		t.Error("expected error without a model")
+	}
+
+	assistant.SetModel(fake.NewFakeLLM([]string{`{}`}), memory.NewConversationBuffer())
+	if _, err := assistant.Explain(context.Background(), "  "); err == nil {
+		t.Error("expected error for an empty command")
+	}
+}
+
+func TestExplainCommand(t *testing.T) {
+	tests := []struct {
+		input   string
+		command string
+		ok      bool
+	}{
+		{input: "/explain ls -la", command: "ls -la", ok: true},
+		{input: "/explain", command: "", ok: true},
+		{input: "/explain   tar xzf a.tgz ", command: "tar xzf a.tgz", ok: true},
+		{input: "/explainer", ok: false},
+		{input: "explain ls", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			command, ok := explainCommand(tt.input)
+			if ok != tt.ok || command != tt.command {
+				t.Errorf("expected (%q, %t) but got (%q, %t)", tt.command, tt.ok, command, ok)
+			}
+		})
+	}
+}
@@ -12,6 +12,9 @@ type Assistant interface {
 	// Run starts the chat session
 	Run(ctx context.Context, llm llms.Model, memory schema.Memory) error
 
+	// SetModel sets the model and memory used by ProcessInput
+	SetModel(llm llms.Model, memory schema.Memory)
+
 	// ProcessInput processes user input and returns AI response
 	ProcessInput(ctx context.Context, input string) (*AssistantResult, error)
 
@@ -30,6 +33,15 @@ type ScriptExecutor interface {
 	// Execute executes a script command
 	Execute(ctx context.Context, command string) (*ExecutionResult, error)
 
+	// ExecuteFile executes a script file
+	ExecuteFile(ctx context.Context, path string) (*ExecutionResult, error)
+
+	// ScriptExtension returns the file extension for scripts, such as ".sh"
+	ScriptExtension() string
+
+	// Shebang returns the interpreter line for script files, or an empty string if none is used
+	Shebang() string
+
 	// CanExecute checks if a command can be executed
 	CanExecute(command string) bool
 
@@ -48,3 +60,15 @@ type PromptLoader interface {
 	// GetAvailableTemplates returns available template names
 	GetAvailableTemplates() []string
 }
+
+// SessionStore persists chat sessions
+type SessionStore interface {
+	// Save writes the session to the store
+	Save(ctx context.Context, session *Session) error
+}
+
+// AuditLog records generated and executed scripts
+type AuditLog interface {
+	// Append adds an entry to the log
+	Append(entry *AuditEntry) error
+}
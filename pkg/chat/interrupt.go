@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// interruptible returns a context for a turn that Ctrl-C cancels while the turn waits for the model or
+// for an answer, so that the chat returns to the prompt. While a script runs, Ctrl-C only stops the script.
+// Reads from standard input in Run give up when the returned context is done.
+func (c *CliAssistant) interruptible(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	go func() {
+		for {
+			select {
+			case <-sigChan:
+				if c.executing.Load() {
+					continue
+				}
+				fmt.Println()
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if c.stdin != nil {
+		c.stdin.setContext(ctx)
+	}
+	return ctx, func() {
+		signal.Stop(sigChan)
+		cancel()
+		if c.stdin != nil {
+			c.stdin.setContext(nil)
+		}
+	}
+}
+
+// interrupted reports whether err was caused by the user cancelling ctx
+func interrupted(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() != nil
+}
+
+// stdinReader reads standard input, giving up with the error of the context of the current turn once it is done
+type stdinReader struct {
+	file *os.File
+
+	mu  sync.Mutex
+	ctx context.Context
+}
+
+// newStdinReader creates a reader of standard input
+func newStdinReader() *stdinReader {
+	return &stdinReader{file: os.Stdin}
+}
+
+// setContext sets the context that interrupts reads, or nil to read without interruption
+func (r *stdinReader) setContext(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = ctx
+}
+
+// Read implements io.Reader
+func (r *stdinReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	ctx := r.ctx
+	r.mu.Unlock()
+
+	if ctx != nil {
+		if err := waitReadable(ctx, r.file); err != nil {
+			return 0, err
+		}
+	}
+	return r.file.Read(p)
+}
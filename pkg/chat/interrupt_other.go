@@ -0,0 +1,13 @@
+//go:build !unix || aix
+
+package chat
+
+import (
+	"context"
+	"os"
+)
+
+// waitReadable only checks ctx on this platform, where reads of standard input cannot be interrupted
+func waitReadable(ctx context.Context, _ *os.File) error {
+	return ctx.Err()
+}
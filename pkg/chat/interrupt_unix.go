@@ -0,0 +1,33 @@
+//go:build unix && !aix
+
+package chat
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pollInterval is how often waitReadable checks whether its context is done
+const pollInterval = 100 * time.Millisecond
+
+// waitReadable waits until file has data to read, returning the error of ctx if it is done first.
+// If file cannot be polled, it returns at once and the read blocks as usual.
+func waitReadable(ctx context.Context, file *os.File) error {
+	fds := []unix.PollFd{{Fd: int32(file.Fd()), Events: unix.POLLIN}}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := unix.Poll(fds, int(pollInterval/time.Millisecond))
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil || n > 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,111 @@
+//go:build unix && !aix
+
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInterruptibleCancelsTurn(t *testing.T) {
+	assistant := newTestAssistant()
+	ctx, stop := assistant.interruptible(context.Background())
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Ctrl-C to cancel the turn")
+	}
+}
+
+func TestInterruptibleKeepsTurnWhileExecuting(t *testing.T) {
+	assistant := newTestAssistant()
+	ctx, stop := assistant.interruptible(context.Background())
+	defer stop()
+
+	assistant.executing.Store(true)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("expected Ctrl-C to leave the turn running while a script runs")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestExecuteScriptLeavesInterruptToCaller(t *testing.T) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer signal.Stop(sigChan)
+
+	assistant := newTestAssistant()
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}()
+	result, err := assistant.ExecuteScript(context.Background(), "sleep 1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Errorf("expected the script to finish but got %+v", result)
+	}
+	select {
+	case <-sigChan:
+	default:
+		t.Error("expected the interrupt to reach the caller")
+	}
+}
+
+func TestStdinReaderInterrupted(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	reader := &stdinReader{file: r}
+	ctx, cancel := context.WithCancel(context.Background())
+	reader.setContext(ctx)
+
+	if _, err := w.WriteString("y\n"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 8)
+	if n, err := reader.Read(buf); err != nil || string(buf[:n]) != "y\n" {
+		t.Fatalf("expected %q but got %q, %v", "y\n", buf[:n], err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := reader.Read(buf)
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v but got %v", context.Canceled, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the read to stop when the turn is cancelled")
+	}
+
+	reader.setContext(nil)
+	w.Close()
+	if _, err := reader.Read(buf); err != io.EOF {
+		t.Errorf("expected %v but got %v", io.EOF, err)
+	}
+}
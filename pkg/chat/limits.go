@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Failure reasons reported in ExecutionResult when a script is stopped or exceeds a limit
+const (
+	FailureTimeout        = "timeout"
+	FailureInterrupted    = "interrupted"
+	FailureCPULimit       = "cpu_limit"
+	FailureMemoryLimit    = "memory_limit"
+	FailureOpenFilesLimit = "open_files_limit"
+	FailureProcessLimit   = "process_limit"
+)
+
+// limitExitCode is the exit code of a script whose limits could not be applied
+const limitExitCode = 126
+
+// ResourceLimits are rlimits applied to executed scripts on Linux.
+// A zero value leaves the corresponding limit unset.
+type ResourceLimits struct {
+	CPUSeconds     uint64
+	AddressSpaceMB uint64
+	OpenFiles      uint64
+	Processes      uint64
+}
+
+// IsZero reports whether no limit is set
+func (l ResourceLimits) IsZero() bool {
+	return l == ResourceLimits{}
+}
+
+// limitScript prefixes command with ulimit calls that apply the limits in the shell before the script runs.
+// The script exits with limitExitCode if a limit cannot be applied.
+func limitScript(shell string, limits ResourceLimits, command string) (string, error) {
+	if limits.IsZero() {
+		return command, nil
+	}
+	if !limitsSupported {
+		return "", fmt.Errorf("resource limits are only supported on Linux")
+	}
+
+	var orExit string
+	switch shell {
+	case ShellBash, ShellSh, ShellZsh:
+		orExit = fmt.Sprintf(" || exit %d", limitExitCode)
+	case ShellFish:
+		orExit = fmt.Sprintf("; or exit %d", limitExitCode)
+	default:
+		return "", fmt.Errorf("resource limits are not supported for shell %s", shell)
+	}
+
+	var lines []string
+	if limits.CPUSeconds > 0 {
+		// The soft limit sends SIGXCPU, which lets the failure be told apart from other kills.
+		// It is lowered first because a hard limit cannot be set below the current soft limit.
+		lines = append(lines,
+			fmt.Sprintf("ulimit -S -t %d%s", limits.CPUSeconds, orExit),
+			fmt.Sprintf("ulimit -H -t %d%s", limits.CPUSeconds+1, orExit))
+	}
+	if limits.AddressSpaceMB > 0 {
+		lines = append(lines, fmt.Sprintf("ulimit -v %d%s", limits.AddressSpaceMB*1024, orExit))
+	}
+	if limits.OpenFiles > 0 {
+		lines = append(lines, fmt.Sprintf("ulimit -n %d%s", limits.OpenFiles, orExit))
+	}
+	if limits.Processes > 0 {
+		if shell == ShellSh {
+			// dash names the process limit -p, while bash in POSIX mode uses -u
+			lines = append(lines, fmt.Sprintf("{ ulimit -u %d 2>/dev/null || ulimit -p %d; }%s", limits.Processes, limits.Processes, orExit))
+		} else {
+			lines = append(lines, fmt.Sprintf("ulimit -u %d%s", limits.Processes, orExit))
+		}
+	}
+
+	return strings.Join(append(lines, command), "\n"), nil
+}
+
+// failureReason classifies why a script failed.
+// ctx is the caller's context and runCtx the context the script ran under, which carries the timeout.
+func failureReason(ctx, runCtx context.Context, runErr error, limits ResourceLimits, stderr string) string {
+	if runErr == nil {
+		return ""
+	}
+	if ctx.Err() != nil {
+		return FailureInterrupted
+	}
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return FailureTimeout
+	}
+	if limits.CPUSeconds > 0 && cpuLimitExceeded(runErr) {
+		return FailureCPULimit
+	}
+
+	stderr = strings.ToLower(stderr)
+	switch {
+	case limits.AddressSpaceMB > 0 && containsAny(stderr, "cannot allocate memory", "out of memory", "memoryerror", "bad_alloc", "memory exhausted"):
+		return FailureMemoryLimit
+	case limits.OpenFiles > 0 && strings.Contains(stderr, "too many open files"):
+		return FailureOpenFilesLimit
+	case limits.Processes > 0 && strings.Contains(stderr, "resource temporarily unavailable"):
+		return FailureProcessLimit
+	}
+	return ""
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+//go:build linux
+
+package chat
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// limitsSupported reports whether resource limits can be applied on this platform
+const limitsSupported = true
+
+// cpuLimitExceeded reports whether a script was stopped by its CPU time limit,
+// either directly by SIGXCPU or through a shell reporting the signal as its exit code
+func cpuLimitExceeded(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() && status.Signal() == syscall.SIGXCPU {
+		return true
+	}
+	return exitErr.ExitCode() == 128+int(syscall.SIGXCPU)
+}
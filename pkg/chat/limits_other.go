@@ -0,0 +1,11 @@
+//go:build !linux
+
+package chat
+
+// limitsSupported reports whether resource limits can be applied on this platform
+const limitsSupported = false
+
+// cpuLimitExceeded always reports false because limits are not applied on this platform
+func cpuLimitExceeded(error) bool {
+	return false
+}
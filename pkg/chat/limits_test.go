@@ -0,0 +1,196 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitScript(t *testing.T) {
+	if !limitsSupported {
+		t.Skip("resource limits are not supported on this platform")
+	}
+
+	tests := []struct {
+		name     string
+		shell    string
+		limits   ResourceLimits
+		contains []string
+		wantErr  bool
+	}{
+		{name: "no limits", shell: ShellBash, contains: []string{"echo hi"}},
+		{
+			name:   "bash",
+			shell:  ShellBash,
+			limits: ResourceLimits{CPUSeconds: 5, AddressSpaceMB: 64, OpenFiles: 32, Processes: 10},
+			contains: []string{
+				"ulimit -S -t 5 || exit 126",
+				"ulimit -H -t 6 || exit 126",
+				"ulimit -v 65536 || exit 126",
+				"ulimit -n 32 || exit 126",
+				"ulimit -u 10 || exit 126",
+				"echo hi",
+			},
+		},
+		{name: "sh process limit", shell: ShellSh, limits: ResourceLimits{Processes: 10}, contains: []string{"ulimit -p 10"}},
+		{name: "fish", shell: ShellFish, limits: ResourceLimits{OpenFiles: 32}, contains: []string{"ulimit -n 32; or exit 126"}},
+		{name: "pwsh unsupported", shell: ShellPwsh, limits: ResourceLimits{OpenFiles: 32}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := limitScript(tt.shell, tt.limits, "echo hi")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(script, want) {
+					t.Errorf("expected script to contain %q but got:\n%s", want, script)
+				}
+			}
+			if !strings.HasSuffix(script, "echo hi") {
+				t.Errorf("expected script to end with the command but got:\n%s", script)
+			}
+		})
+	}
+}
+
+func TestShellExecutorTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available on windows")
+	}
+
+	executor := NewShExecutor()
+	executor.SetExecOptions(&ExecOptions{Timeout: 100 * time.Millisecond})
+	if !executor.CanExecute("sleep 5") {
+		t.Skip("sh is not available")
+	}
+
+	start := time.Now()
+	result, err := executor.Execute(context.Background(), "sleep 5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Success || result.FailureReason != FailureTimeout {
+		t.Errorf("expected failure reason %q but got %q", FailureTimeout, result.FailureReason)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("expected script to stop at the timeout but it ran for %s", elapsed)
+	}
+}
+
+func TestShellExecutorInterrupted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available on windows")
+	}
+
+	executor := NewShExecutor()
+	if !executor.CanExecute("sleep 5") {
+		t.Skip("sh is not available")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err := executor.Execute(ctx, "sleep 5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FailureReason != FailureInterrupted {
+		t.Errorf("expected failure reason %q but got %q", FailureInterrupted, result.FailureReason)
+	}
+}
+
+func TestShellExecutorOpenFilesLimit(t *testing.T) {
+	if !limitsSupported {
+		t.Skip("resource limits are not supported on this platform")
+	}
+
+	executor := NewBashExecutor()
+	executor.SetExecOptions(&ExecOptions{Limits: ResourceLimits{OpenFiles: 8}})
+	if !executor.CanExecute("true") {
+		t.Skip("bash is not available")
+	}
+
+	result, err := executor.Execute(context.Background(), "ulimit -n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.TrimSpace(result.Stdout) != "8" {
+		t.Errorf("expected open files limit 8 but got %q (%s)", result.Stdout, result.Stderr)
+	}
+}
+
+func TestFailureReason(t *testing.T) {
+	allLimits := ResourceLimits{CPUSeconds: 1, AddressSpaceMB: 64, OpenFiles: 32, Processes: 10}
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	failed := errors.New("exit status 1")
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		runCtx   context.Context
+		err      error
+		limits   ResourceLimits
+		stderr   string
+		expected string
+	}{
+		{name: "success", err: nil, expected: ""},
+		{name: "plain failure", err: failed, expected: ""},
+		{name: "timeout", runCtx: expired, err: failed, expected: FailureTimeout},
+		{name: "interrupted", ctx: cancelled, runCtx: cancelled, err: failed, expected: FailureInterrupted},
+		{name: "memory", err: failed, limits: allLimits, stderr: "malloc: Cannot allocate memory", expected: FailureMemoryLimit},
+		{name: "open files", err: failed, limits: allLimits, stderr: "Too many open files", expected: FailureOpenFilesLimit},
+		{name: "processes", err: failed, limits: allLimits, stderr: "fork: retry: Resource temporarily unavailable", expected: FailureProcessLimit},
+		{name: "limit not set", err: failed, stderr: "Too many open files", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, runCtx := tt.ctx, tt.runCtx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if runCtx == nil {
+				runCtx = ctx
+			}
+			if got := failureReason(ctx, runCtx, tt.err, tt.limits, tt.stderr); got != tt.expected {
+				t.Errorf("expected %q but got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestShellExecutorCPULimit(t *testing.T) {
+	if !limitsSupported {
+		t.Skip("resource limits are not supported on this platform")
+	}
+	if testing.Short() {
+		t.Skip("skipping CPU limit test in short mode")
+	}
+
+	executor := NewBashExecutor()
+	executor.SetExecOptions(&ExecOptions{Limits: ResourceLimits{CPUSeconds: 1}, Timeout: 10 * time.Second})
+	if !executor.CanExecute("true") {
+		t.Skip("bash is not available")
+	}
+
+	result, err := executor.Execute(context.Background(), "while :; do :; done")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FailureReason != FailureCPULimit {
+		t.Errorf("expected failure reason %q but got %q (error: %s)", FailureCPULimit, result.FailureReason, result.Error)
+	}
+}
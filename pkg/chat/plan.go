@@ -0,0 +1,140 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Step statuses reported in the plan summary
+const (
+	stepPending = "not run"
+	stepDone    = "done"
+	stepFailed  = "failed"
+	stepSkipped = "skipped"
+)
+
+// stepAction is the user's choice after a step fails or is not executed
+type stepAction int
+
+const (
+	stepAbort stepAction = iota
+	stepRetry
+	stepSkip
+)
+
+// stepOutcome records what happened to a plan step
+type stepOutcome struct {
+	status string
+	result *ExecutionResult
+}
+
+// runPlan walks through the steps of a plan, confirming and executing each one.
+// A step that fails or is not executed can be retried, skipped or the plan aborted.
+// The outcome of every step is passed to the model in the next turn.
+func (c *CliAssistant) runPlan(ctx context.Context, reader *bufio.Reader, steps []PlanStep) {
+	fmt.Printf("\nPlan with %d steps:\n", len(steps))
+	for i, step := range steps {
+		fmt.Printf("  %d. %s\n", i+1, step.Description)
+	}
+
+	outcomes := make([]stepOutcome, len(steps))
+	for i := range outcomes {
+		outcomes[i].status = stepPending
+	}
+
+	for i := 0; i < len(steps); {
+		step := steps[i]
+		fmt.Printf("\n── Step %d/%d: %s\n", i+1, len(steps), step.Description)
+		if step.Expected != "" {
+			fmt.Printf("Expected: %s\n", step.Expected)
+		}
+		if !isMultiLine(step.Script) {
+			fmt.Printf("Script: %s\n", strings.TrimSpace(step.Script))
+		}
+
+		result := c.confirmAndExecuteScript(ctx, reader, step.Script)
+		if result != nil {
+			outcomes[i].result = result
+		}
+		if result != nil && result.Success {
+			outcomes[i].status = stepDone
+			i++
+			continue
+		}
+		if result != nil {
+			outcomes[i].status = stepFailed
+		}
+
+		action, err := askStepAction(reader)
+		if interrupted(ctx, err) {
+			fmt.Println("Plan aborted.")
+			break
+		}
+		if err != nil {
+			c.logger.WithError(err).Error("Failed to read step action")
+			break
+		}
+		if action == stepAbort {
+			fmt.Println("Plan aborted.")
+			break
+		}
+		if action == stepSkip {
+			outcomes[i].status = stepSkipped
+			i++
+		}
+	}
+
+	c.lastPlan = planSummary(steps, outcomes)
+	fmt.Println()
+	fmt.Println(c.lastPlan)
+}
+
+// askStepAction asks whether to retry or skip a step, or abort the plan
+func askStepAction(reader *bufio.Reader) (stepAction, error) {
+	fmt.Println("\n(r)etry this step, (s)kip it, or (a)bort the plan?")
+	fmt.Print("You: ")
+
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		return stepAbort, fmt.Errorf("failed to read step action: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "r", "retry":
+		return stepRetry, nil
+	case "s", "skip":
+		return stepSkip, nil
+	default:
+		return stepAbort, nil
+	}
+}
+
+// planSummary describes the outcome of every step for the user and the model
+func planSummary(steps []PlanStep, outcomes []stepOutcome) string {
+	var b strings.Builder
+	b.WriteString("Plan results:")
+	for i, step := range steps {
+		outcome := outcomes[i]
+		fmt.Fprintf(&b, "\n%d. %s: %s", i+1, step.Description, outcome.status)
+		if outcome.result == nil {
+			continue
+		}
+		fmt.Fprintf(&b, " (exit code %d)", outcome.result.ExitCode)
+		if outcome.status == stepFailed {
+			if stderr := lastLines(outcome.result.Stderr, 5); stderr != "" {
+				fmt.Fprintf(&b, "\n   %s", strings.ReplaceAll(stderr, "\n", "\n   "))
+			}
+		}
+	}
+	return b.String()
+}
+
+// lastLines returns at most n trailing non-empty lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestPlanSummary(t *testing.T) {
+	steps := []PlanStep{
+		{Description: "create dir", Script: "mkdir out"},
+		{Description: "copy files", Script: "cp *.txt out"},
+		{Description: "skip me", Script: "true"},
+		{Description: "archive", Script: "tar czf out.tgz out"},
+	}
+	outcomes := []stepOutcome{
+		{status: stepDone, result: &ExecutionResult{Success: true, ExitCode: 0}},
+		{status: stepFailed, result: &ExecutionResult{ExitCode: 1, Stderr: "line1\nline2\nline3\nline4\nline5\ncp: cannot stat '*.txt'\n"}},
+		{status: stepSkipped},
+		{status: stepPending},
+	}
+
+	expected := "Plan results:\n" +
+		"1. create dir: done (exit code 0)\n" +
+		"2. copy files: failed (exit code 1)\n" +
+		"   line2\n   line3\n   line4\n   line5\n   cp: cannot stat '*.txt'\n" +
+		"3. skip me: skipped\n" +
+		"4. archive: not run"
+	if got := planSummary(steps, outcomes); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestAskStepAction(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected stepAction
+	}{
+		{input: "r\n", expected: stepRetry},
+		{input: "Retry\n", expected: stepRetry},
+		{input: "s\n", expected: stepSkip},
+		{input: "a\n", expected: stepAbort},
+		{input: "\n", expected: stepAbort},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.input), func(t *testing.T) {
+			action, err := askStepAction(bufio.NewReader(strings.NewReader(tt.input)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if action != tt.expected {
+				t.Errorf("expected %v but got %v", tt.expected, action)
+			}
+		})
+	}
+}
+
+func TestWithLastPlan(t *testing.T) {
+	assistant := newTestAssistant()
+	assistant.lastExecResult = &ExecutionResult{Success: true, Command: "ls"}
+	assistant.lastPlan = "Plan results:\n1. list: done (exit code 0)"
+
+	got := assistant.withLastExecResult("next")
+	if got != "Plan results:\n1. list: done (exit code 0)\nnext" {
+		t.Errorf("expected the plan summary to be prepended, got %q", got)
+	}
+
+	got = assistant.withLastExecResult("again")
+	if !strings.HasPrefix(got, "Last execution result: ") {
+		t.Errorf("expected the plan summary to be sent once, got %q", got)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix || aix
+
+package chat
+
+import "os/exec"
+
+// processGroup does nothing on this platform, where cancelling a command only kills the shell
+func processGroup(*exec.Cmd) func() {
+	return func() {}
+}
@@ -0,0 +1,49 @@
+//go:build unix && !aix
+
+package chat
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// processGroup runs the command in a process group of its own and makes cancelling it kill the whole group,
+// so that children of the shell, such as the commands of a pipeline or background jobs, stop with it.
+// When autocmdr runs in the foreground of a terminal, the group is moved to the foreground while the script
+// runs, so that programs reading the terminal, such as sudo asking for a password, are not stopped.
+// The returned function gives the terminal back and must be called once the command has finished.
+func processGroup(cmd *exec.Cmd) func() {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return func() {}
+	}
+	pgrp, err := unix.Getpgid(0)
+	if err != nil {
+		tty.Close()
+		return func() {}
+	}
+	if foreground, err := unix.IoctlGetInt(int(tty.Fd()), unix.TIOCGPGRP); err != nil || foreground != pgrp {
+		tty.Close()
+		return func() {}
+	}
+	cmd.ExtraFiles = append(cmd.ExtraFiles, tty)
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = 2 + len(cmd.ExtraFiles)
+
+	return func() {
+		// A background process group is stopped by SIGTTOU when it takes the terminal back
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		_ = unix.IoctlSetPointerInt(int(tty.Fd()), unix.TIOCSPGRP, pgrp)
+		tty.Close()
+	}
+}
@@ -0,0 +1,70 @@
+//go:build unix && !aix
+
+package chat
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShellExecutorTimeoutKillsChildren(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{name: "background job", script: "sleep 30 & echo $!; wait"},
+		{name: "pipeline", script: "sh -c 'echo $$; exec sleep 30' | cat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewShExecutor()
+			executor.SetExecOptions(&ExecOptions{Timeout: 500 * time.Millisecond})
+			if !executor.CanExecute(tt.script) {
+				t.Skip("sh is not available")
+			}
+
+			result, err := executor.Execute(context.Background(), tt.script)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.FailureReason != FailureTimeout {
+				t.Errorf("expected failure reason %q but got %q", FailureTimeout, result.FailureReason)
+			}
+			pid, err := strconv.Atoi(strings.TrimSpace(result.Stdout))
+			if err != nil {
+				t.Fatalf("expected the pid of the child but got %q", result.Stdout)
+			}
+
+			deadline := time.Now().Add(2 * time.Second)
+			for processRunning(pid) {
+				if time.Now().After(deadline) {
+					syscall.Kill(pid, syscall.SIGKILL)
+					t.Fatalf("child %d is still running after the timeout", pid)
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+		})
+	}
+}
+
+// processRunning reports whether a process exists and has not exited, counting unreaped zombies as exited
+func processRunning(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		// Without /proc, the process is taken to be running since it exists
+		return true
+	}
+	// The state follows the command name, which is in parentheses
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
@@ -0,0 +1,27 @@
+package chat
+
+import "fmt"
+
+// repairPrompt asks the model to answer again after its response could not be parsed
+func repairPrompt(err error) string {
+	return fmt.Sprintf("Your previous response could not be used: %v\n"+
+		"Reply again with only the JSON object in the required output format. "+
+		"Booleans must be true or false without quotes.", err)
+}
+
+// parseWithRepair parses resp, asking the model for a corrected response up to MaxRepairAttempts times
+// while parse fails. ask receives the attempt number and the parse error, and returns the new response.
+func (c *CliAssistant) parseWithRepair(resp string, parse func(resp string) error, ask func(attempt int, parseErr error) (string, error)) error {
+	err := parse(resp)
+	for attempt := 1; err != nil && attempt <= c.options.MaxRepairAttempts; attempt++ {
+		c.logger.WithError(err).WithField("attempt", attempt).Debug("Invalid AI response, asking for a corrected one")
+
+		var askErr error
+		resp, askErr = ask(attempt, err)
+		if askErr != nil {
+			return askErr
+		}
+		err = parse(resp)
+	}
+	return err
+}
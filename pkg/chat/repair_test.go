@@ -0,0 +1,109 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms/fake"
+	"github.com/tmc/langchaingo/memory"
+
+	"github.com/blysin/autocmdr/pkg/validate"
+)
+
+func TestProcessInputRepairsInvalidResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []string
+		attempts  int
+		script    string
+		wantErr   string
+	}{
+		{
+			name:      "repaired on the first retry",
+			responses: []string{`{"multipleLines": false, "script": "ls"}`, `{"success": true, "script": "ls"}`},
+			attempts:  2,
+			script:    "ls",
+		},
+		{
+			name:      "repaired on the last retry",
+			responses: []string{"no JSON here", `{"success": "maybe", "script": "ls"}`, `{"success": true, "script": "pwd"}`},
+			attempts:  2,
+			script:    "pwd",
+		},
+		{
+			name:      "gives up after the attempts",
+			responses: []string{`{"script": "ls"}`, `{"script": "ls"}`, `{"success": true, "script": "ls"}`},
+			attempts:  1,
+			wantErr:   `missing required field "success"`,
+		},
+		{
+			name:      "repair disabled",
+			responses: []string{`{"success": true}`, `{"success": true, "script": "ls"}`},
+			attempts:  0,
+			wantErr:   `response must have one of the fields "script", "steps"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assistant := newTestAssistant()
+			assistant.options.MaxRepairAttempts = tt.attempts
+			log := &memoryAuditLog{}
+			assistant.SetAuditLog(log)
+			assistant.SetModel(fake.NewFakeLLM(tt.responses), memory.NewConversationBuffer())
+
+			result, err := assistant.ProcessInput(context.Background(), "list files")
+			assistant.FlushAudit()
+
+			if tt.wantErr != "" {
+				var invalid *validate.Error
+				if !errors.As(err, &invalid) {
+					t.Fatalf("expected a validation error but got %v", err)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error to contain %q but got %q", tt.wantErr, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Script != tt.script {
+				t.Errorf("expected script %q but got %q", tt.script, result.Script)
+			}
+
+			last := log.entries[len(log.entries)-1]
+			if last.Result == nil || last.Result.Script != tt.script {
+				t.Errorf("expected the repaired result to be audited, got %+v", last.Result)
+			}
+			if !strings.Contains(last.Prompt, "could not be used") {
+				t.Errorf("expected the repair prompt to be audited, got %q", last.Prompt)
+			}
+		})
+	}
+}
+
+func TestExplainRepairsInvalidResponse(t *testing.T) {
+	assistant := newTestAssistant()
+	assistant.SetModel(fake.NewFakeLLM([]string{
+		`{"parts": []}`,
+		`{"summary": "Lists files", "risk": "read-only"}`,
+	}), memory.NewConversationBuffer())
+
+	explanation, err := assistant.Explain(context.Background(), "ls")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if explanation.Summary != "Lists files" {
+		t.Errorf("expected the repaired explanation, got %+v", explanation)
+	}
+}
+
+func TestRepairPrompt(t *testing.T) {
+	prompt := repairPrompt(&validate.Error{Problems: []string{`missing required field "success"`}})
+	if !strings.Contains(prompt, `invalid response: missing required field "success"`) {
+		t.Errorf("expected the validation error in the prompt, got %q", prompt)
+	}
+}
@@ -0,0 +1,163 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/chzyer/readline"
+
+	"github.com/blysin/autocmdr/pkg/utils"
+)
+
+// isMultiLine reports whether a script spans more than one line
+func isMultiLine(script string) bool {
+	return strings.Contains(strings.TrimSpace(script), "\n")
+}
+
+// scriptFileContent returns the script with the shebang prepended, unless it already starts with one
+func scriptFileContent(shebang, script string) string {
+	script = strings.TrimSpace(script) + "\n"
+	if shebang == "" || strings.HasPrefix(script, "#!") {
+		return script
+	}
+	return shebang + "\n" + script
+}
+
+// writeScriptFile writes a script to a new temporary file for the executor's shell and returns its path
+func writeScriptFile(executor ScriptExecutor, script string) (string, error) {
+	f, err := utils.CreateTempFile("autocmdr-", executor.ScriptExtension())
+	if err != nil {
+		return "", fmt.Errorf("failed to create script file: %w", err)
+	}
+	path := f.Name()
+
+	if _, err := f.WriteString(scriptFileContent(executor.Shebang(), script)); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return "", fmt.Errorf("failed to write script file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(path)
+		return "", fmt.Errorf("failed to write script file: %w", err)
+	}
+	if err := os.Chmod(path, 0o700); err != nil {
+		_ = os.Remove(path)
+		return "", fmt.Errorf("failed to make script file executable: %w", err)
+	}
+	return path, nil
+}
+
+// saveScriptFile writes a script to path as an executable file, expanding a leading ~
+func saveScriptFile(executor ScriptExecutor, script, path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	if filepath.Ext(path) == "" {
+		path += executor.ScriptExtension()
+	}
+
+	if err := os.WriteFile(path, []byte(scriptFileContent(executor.Shebang(), script)), 0o755); err != nil {
+		return "", fmt.Errorf("failed to save script: %w", err)
+	}
+	return path, nil
+}
+
+// printNumbered prints a script with line numbers
+func printNumbered(script string) {
+	lines := strings.Split(strings.TrimSpace(script), "\n")
+	width := len(fmt.Sprint(len(lines)))
+	for i, line := range lines {
+		fmt.Printf("%*d | %s\n", width, i+1, line)
+	}
+}
+
+// editInline lets the user edit a single-line script with readline, prefilled with the script.
+// Interrupting the edit keeps the script unchanged.
+func editInline(script string) (string, error) {
+	rl, err := readline.New("Edit: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to start line editor: %w", err)
+	}
+	defer func() {
+		_ = rl.Close()
+	}()
+
+	if _, err := rl.WriteStdin([]byte(script)); err != nil {
+		return "", fmt.Errorf("failed to prefill line editor: %w", err)
+	}
+	line, err := rl.Readline()
+	if errors.Is(err, readline.ErrInterrupt) {
+		return script, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited script: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// editScript edits a script inline if it is a single line, or in the user's editor otherwise
+func editScript(executor ScriptExecutor, script string) (string, error) {
+	if isMultiLine(script) {
+		return editInEditor(executor, script)
+	}
+	return editInline(script)
+}
+
+// editInEditor opens the script in $VISUAL or $EDITOR and returns the edited content
+func editInEditor(executor ScriptExecutor, script string) (string, error) {
+	path, err := writeScriptFile(executor, script)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.Remove(path)
+	}()
+
+	editor := strings.Fields(editorCommand())
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited script: %w", err)
+	}
+	return stripShebang(executor.Shebang(), string(data)), nil
+}
+
+// editorCommand returns the user's editor, falling back to a platform default
+func editorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// stripShebang removes the shebang that writeScriptFile added so that the script can be compared and re-written
+func stripShebang(shebang, script string) string {
+	script = strings.TrimSpace(script)
+	if shebang != "" {
+		if rest, ok := strings.CutPrefix(script, shebang+"\n"); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	return script
+}
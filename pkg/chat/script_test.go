@@ -0,0 +1,168 @@
+package chat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestScriptFileContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		shebang  string
+		script   string
+		expected string
+	}{
+		{name: "adds shebang", shebang: "#!/bin/sh", script: "echo a\necho b", expected: "#!/bin/sh\necho a\necho b\n"},
+		{name: "keeps existing shebang", shebang: "#!/bin/sh", script: "#!/usr/bin/env bash\necho a", expected: "#!/usr/bin/env bash\necho a\n"},
+		{name: "no shebang", shebang: "", script: "Get-ChildItem\n", expected: "Get-ChildItem\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scriptFileContent(tt.shebang, tt.script)
+			if got != tt.expected {
+				t.Errorf("expected %q but got %q", tt.expected, got)
+			}
+			if stripped := stripShebang(tt.shebang, got); tt.shebang != "" && !strings.HasPrefix(tt.script, "#!") && stripped != strings.TrimSpace(tt.script) {
+				t.Errorf("expected shebang to be stripped back to %q but got %q", tt.script, stripped)
+			}
+		})
+	}
+}
+
+func TestScriptExtensionAndShebang(t *testing.T) {
+	tests := []struct {
+		executor  *ShellExecutor
+		extension string
+		shebang   string
+	}{
+		{executor: NewBashExecutor(), extension: ".sh", shebang: "#!/usr/bin/env bash"},
+		{executor: NewShExecutor(), extension: ".sh", shebang: "#!/bin/sh"},
+		{executor: NewZshExecutor(), extension: ".sh", shebang: "#!/usr/bin/env zsh"},
+		{executor: NewFishExecutor(), extension: ".fish", shebang: "#!/usr/bin/env fish"},
+		{executor: NewPwshExecutor(), extension: ".ps1", shebang: "#!/usr/bin/env pwsh"},
+		{executor: NewPowershellExecutor(), extension: ".ps1", shebang: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.executor.GetShell(), func(t *testing.T) {
+			if got := tt.executor.ScriptExtension(); got != tt.extension {
+				t.Errorf("expected extension %q but got %q", tt.extension, got)
+			}
+			if got := tt.executor.Shebang(); got != tt.shebang {
+				t.Errorf("expected shebang %q but got %q", tt.shebang, got)
+			}
+		})
+	}
+}
+
+func TestIsMultiLine(t *testing.T) {
+	tests := map[string]bool{
+		"ls -la":                 false,
+		"ls -la\n":               false,
+		"cd /tmp\nls":            true,
+		"for f in *; do\n  echo": true,
+	}
+	for script, expected := range tests {
+		if got := isMultiLine(script); got != expected {
+			t.Errorf("isMultiLine(%q): expected %v but got %v", script, expected, got)
+		}
+	}
+}
+
+func TestExecuteMultiLineScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available on windows")
+	}
+
+	assistant := newTestAssistant()
+	if !assistant.executor.CanExecute("true") {
+		t.Skip("sh is not available")
+	}
+
+	script := "x=multi\necho \"$x\"\necho \"${0##*.}\""
+	result, err := assistant.ExecuteScript(context.Background(), script)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("expected success but got %q (%s)", result.Error, result.Stderr)
+	}
+	if result.Stdout != "multi\nsh\n" {
+		t.Errorf("expected the script to run from a .sh file but got %q", result.Stdout)
+	}
+	if result.Command != script {
+		t.Errorf("expected the script to be recorded as the command but got %q", result.Command)
+	}
+}
+
+func TestExecuteFileWithLimits(t *testing.T) {
+	if !limitsSupported {
+		t.Skip("resource limits are not supported on this platform")
+	}
+
+	executor := NewBashExecutor()
+	executor.SetExecOptions(&ExecOptions{Limits: ResourceLimits{OpenFiles: 16}})
+	if !executor.CanExecute("true") {
+		t.Skip("bash is not available")
+	}
+
+	path, err := writeScriptFile(executor, "echo start\nulimit -n")
+	if err != nil {
+		t.Fatalf("failed to write script file: %v", err)
+	}
+	defer os.Remove(path)
+
+	result, err := executor.ExecuteFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Stdout != "start\n16\n" {
+		t.Errorf("expected limits to apply to the script file but got %q (%s)", result.Stdout, result.Stderr)
+	}
+}
+
+func TestSaveScriptFile(t *testing.T) {
+	dir := t.TempDir()
+	executor := NewShExecutor()
+
+	path, err := saveScriptFile(executor, "echo a\necho b", filepath.Join(dir, "cleanup"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(dir, "cleanup.sh") {
+		t.Errorf("expected extension to be added but got %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read saved script: %v", err)
+	}
+	if string(data) != "#!/bin/sh\necho a\necho b\n" {
+		t.Errorf("unexpected saved content %q", data)
+	}
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("expected saved script to be executable but mode is %v", info.Mode())
+	}
+}
+
+func TestQuotePath(t *testing.T) {
+	tests := []struct {
+		shell    string
+		path     string
+		expected string
+	}{
+		{shell: ShellBash, path: "/tmp/a b.sh", expected: "'/tmp/a b.sh'"},
+		{shell: ShellBash, path: "/tmp/it's.sh", expected: `'/tmp/it'\''s.sh'`},
+		{shell: ShellFish, path: "/tmp/it's.fish", expected: `'/tmp/it\'s.fish'`},
+	}
+	for _, tt := range tests {
+		if got := quotePath(tt.shell, tt.path); got != tt.expected {
+			t.Errorf("quotePath(%q, %q): expected %s but got %s", tt.shell, tt.path, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/memory"
+)
+
+// NewSession creates an empty session with window memory sized from the options
+func NewSession(id string, options *Options) *Session {
+	if options == nil {
+		options = DefaultChatOptions()
+	}
+	now := time.Now()
+	s := &Session{
+		ID:        id,
+		Options:   options,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.Restore(options)
+	return s
+}
+
+// Restore rebuilds the session memory from its transcript, keeping the most recent messages
+func (s *Session) Restore(options *Options) {
+	if options == nil {
+		options = DefaultChatOptions()
+	}
+	s.Options = options
+
+	messages := make([]llms.ChatMessage, 0, len(s.Messages))
+	for _, msg := range s.Messages {
+		switch msg.Role {
+		case RoleHuman:
+			messages = append(messages, llms.HumanChatMessage{Content: msg.Content})
+		case RoleAI:
+			messages = append(messages, llms.AIChatMessage{Content: msg.Content})
+		}
+	}
+	if limit := options.MemorySize * 2; limit > 0 && len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+
+	history := memory.NewChatMessageHistory(memory.WithPreviousMessages(messages))
+	s.Memory = memory.NewConversationWindowBuffer(options.MemorySize, memory.WithChatHistory(history))
+}
+
+// AddMessage appends a message to the transcript
+func (s *Session) AddMessage(role, content string) {
+	now := time.Now()
+	s.Messages = append(s.Messages, Message{Role: role, Content: content, Time: now})
+	s.UpdatedAt = now
+}
+
+// AddExecution records a script execution
+func (s *Session) AddExecution(result *ExecutionResult) {
+	s.Executions = append(s.Executions, result)
+	s.LastExec = result
+	s.UpdatedAt = time.Now()
+}
+
+// Clear removes the transcript and clears the memory, keeping the execution history
+func (s *Session) Clear(ctx context.Context) error {
+	s.Messages = nil
+	s.UpdatedAt = time.Now()
+	return s.Memory.Clear(ctx)
+}
@@ -0,0 +1,28 @@
+package chat
+
+import (
+	"fmt"
+)
+
+// templateInteractive lists the system prompt templates in the REPL, or switches to the named one
+func (c *CliAssistant) templateInteractive(name string) {
+	if name == "" {
+		current := c.promptLoader.Template()
+		fmt.Println("Prompt templates:")
+		for _, template := range c.promptLoader.SystemTemplates() {
+			marker := "  "
+			if template == current {
+				marker = "* "
+			}
+			fmt.Printf("%s%s\n", marker, template)
+		}
+		fmt.Println("Use /template <name> to switch.")
+		return
+	}
+
+	if err := c.SetTemplate(name); err != nil {
+		c.logger.WithError(err).Error("Failed to switch template")
+		return
+	}
+	fmt.Printf("Using the %s prompt template.\n", name)
+}
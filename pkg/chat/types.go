@@ -2,15 +2,80 @@ package chat
 
 import (
 	"context"
+	"time"
 
 	"github.com/tmc/langchaingo/schema"
+
+	"github.com/blysin/autocmdr/pkg/validate"
 )
 
 // AssistantResult represents the result from the AI assistant
 type AssistantResult struct {
-	Success       bool   `json:"success"`
-	MultipleLines bool   `json:"multipleLines"`
-	Script        string `json:"script"`
+	Success       bool       `json:"success"`
+	MultipleLines bool       `json:"multipleLines"`
+	Script        string     `json:"script"`
+	Steps         []PlanStep `json:"steps,omitempty"`
+}
+
+// PlanStep is one step of a multi-step plan
+type PlanStep struct {
+	Description string `json:"description"`
+	Script      string `json:"script"`
+	Expected    string `json:"expected"`
+}
+
+// resultSchema is the JSON expected from the model for an AssistantResult
+var resultSchema = &validate.Field{
+	Type:  validate.Object,
+	OneOf: []string{"script", "steps"},
+	Fields: []validate.Field{
+		{Name: "success", Type: validate.Boolean, Required: true},
+		{Name: "multipleLines", Type: validate.Boolean},
+		{Name: "script", Type: validate.String},
+		{Name: "steps", Type: validate.Array, Elem: &validate.Field{
+			Type: validate.Object,
+			Fields: []validate.Field{
+				{Name: "description", Type: validate.String, Required: true},
+				{Name: "script", Type: validate.String, Required: true},
+				{Name: "expected", Type: validate.String},
+			},
+		}},
+	},
+}
+
+// Explanation is the model's breakdown of a command, as returned by Explain
+type Explanation struct {
+	Summary     string            `json:"summary"`
+	Parts       []ExplanationPart `json:"parts"`
+	SideEffects []string          `json:"sideEffects"`
+	Risk        string            `json:"risk"`
+	RiskReason  string            `json:"riskReason"`
+}
+
+// ExplanationPart describes one program, flag, argument, pipe or operator of a command
+type ExplanationPart struct {
+	Text        string `json:"text"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+}
+
+// explanationSchema is the JSON expected from the model for an Explanation
+var explanationSchema = &validate.Field{
+	Type: validate.Object,
+	Fields: []validate.Field{
+		{Name: "summary", Type: validate.String, Required: true},
+		{Name: "parts", Type: validate.Array, Elem: &validate.Field{
+			Type: validate.Object,
+			Fields: []validate.Field{
+				{Name: "text", Type: validate.String, Required: true},
+				{Name: "kind", Type: validate.String},
+				{Name: "description", Type: validate.String, Required: true},
+			},
+		}},
+		{Name: "sideEffects", Type: validate.Array, Elem: &validate.Field{Type: validate.String}},
+		{Name: "risk", Type: validate.String},
+		{Name: "riskReason", Type: validate.String},
+	},
 }
 
 // Options contains options for the chat session
@@ -18,13 +83,27 @@ type Options struct {
 	SystemPrompt   string
 	MemorySize     int
 	StreamResponse bool
+	// AutoFix asks the model for a corrected script when an executed script fails
+	AutoFix bool
+	// MaxFixAttempts limits how many corrected scripts are requested for one failure
+	MaxFixAttempts int
+	// MaxRepairAttempts limits how many times the model is asked again for a response that could not be parsed
+	MaxRepairAttempts int
+	// PlanMode asks the model for an ordered list of steps instead of a single script
+	PlanMode bool
+	// AgentMode lets the model call read-only tools to inspect the system before answering
+	AgentMode bool
+	// Language selects the language of the prompts, such as "en" or "zh"; empty uses the default
+	Language string
 }
 
 // DefaultChatOptions returns default chat options
 func DefaultChatOptions() *Options {
 	return &Options{
-		MemorySize:     10,
-		StreamResponse: true,
+		MemorySize:        10,
+		StreamResponse:    true,
+		MaxFixAttempts:    3,
+		MaxRepairAttempts: 2,
 	}
 }
 
@@ -33,18 +112,71 @@ type StreamFunc func(ctx context.Context, chunk []byte) error
 
 // ExecutionResult represents the result of script execution
 type ExecutionResult struct {
-	Success  bool   `json:"success"`
-	Output   string `json:"output"`
-	Error    string `json:"error,omitempty"`
-	ExitCode int    `json:"exit_code"`
-	Duration string `json:"duration"`
-	Command  string `json:"command"`
+	Success   bool   `json:"success"`
+	Output    string `json:"output"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Error     string `json:"error,omitempty"`
+	ExitCode  int    `json:"exit_code"`
+	Duration  string `json:"duration"`
+	Command   string `json:"command"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Streamed  bool   `json:"streamed,omitempty"`
+	// FailureReason is set when the script was stopped or exceeded a limit, e.g. FailureTimeout
+	FailureReason string `json:"failure_reason,omitempty"`
 }
 
 // Session represents a chat session state
 type Session struct {
-	ID       string
-	Memory   schema.Memory
-	Options  *Options
-	LastExec *ExecutionResult
+	ID         string             `json:"id"`
+	Memory     schema.Memory      `json:"-"`
+	Options    *Options           `json:"-"`
+	LastExec   *ExecutionResult   `json:"last_exec,omitempty"`
+	Messages   []Message          `json:"messages"`
+	Executions []*ExecutionResult `json:"executions"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// Message is a single message in a session transcript
+type Message struct {
+	Role    string    `json:"role"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+// Message roles
+const (
+	RoleHuman = "human"
+	RoleAI    = "ai"
+)
+
+// Approval records how a script was approved for execution
+type Approval string
+
+// Approvals of executed scripts
+const (
+	// ApprovalUser means the user confirmed the script at the prompt
+	ApprovalUser Approval = "user"
+	// ApprovalPolicy means the policy auto-approved the script without asking
+	ApprovalPolicy Approval = "policy"
+	// ApprovalExecFlag means the script ran without asking because of the -exec flag
+	ApprovalExecFlag Approval = "exec-flag"
+	// ApprovalCaller means a program calling ExecuteScript ran the script without asking
+	ApprovalCaller Approval = "caller"
+)
+
+// AuditEntry records one model turn and what happened to the generated script.
+// Confirmed is only set when the user confirmed the script at the prompt; Approval records how it was approved.
+type AuditEntry struct {
+	Time      time.Time        `json:"time"`
+	SessionID string           `json:"session_id,omitempty"`
+	Prompt    string           `json:"prompt"`
+	Response  string           `json:"response"`
+	Result    *AssistantResult `json:"result,omitempty"`
+	Confirmed bool             `json:"confirmed"`
+	Approval  Approval         `json:"approval,omitempty"`
+	Execution *ExecutionResult `json:"execution,omitempty"`
+	// Executions holds every step that ran when the response was a plan; Execution is the last of them
+	Executions []*ExecutionResult `json:"executions,omitempty"`
 }
@@ -6,18 +6,47 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+
+	"github.com/blysin/autocmdr/pkg/prompts"
+	"github.com/blysin/autocmdr/pkg/sysinfo"
 )
 
 // Config holds the application configuration
 type Config struct {
+	Provider  string `mapstructure:"provider" json:"provider"`
 	Model     string `mapstructure:"model" json:"model"`
 	ServerURL string `mapstructure:"server_url" json:"server_url"`
 	Token     string `mapstructure:"token" json:"token"`
 	LogLevel  string `mapstructure:"log_level" json:"log_level"`
 	ConfigDir string `mapstructure:"config_dir" json:"config_dir"`
+	Shell     string `mapstructure:"shell" json:"shell"`
+	// MaxOutputBytes caps the script output kept per stream after execution; zero or less means no limit
+	MaxOutputBytes int `mapstructure:"max_output_bytes" json:"max_output_bytes"`
+	// ExecTimeout stops a script after this wall-clock duration; zero means no timeout
+	ExecTimeout time.Duration `mapstructure:"exec_timeout" json:"exec_timeout"`
+	// Resource limits applied to scripts on Linux; zero leaves a limit unset
+	LimitCPUSeconds     uint64 `mapstructure:"limit_cpu_seconds" json:"limit_cpu_seconds"`
+	LimitAddressSpaceMB uint64 `mapstructure:"limit_address_space_mb" json:"limit_address_space_mb"`
+	LimitOpenFiles      uint64 `mapstructure:"limit_open_files" json:"limit_open_files"`
+	LimitProcesses      uint64 `mapstructure:"limit_processes" json:"limit_processes"`
+	// AutoFix asks the model for a corrected script when an executed script fails
+	AutoFix        bool `mapstructure:"auto_fix" json:"auto_fix"`
+	MaxFixAttempts int  `mapstructure:"max_fix_attempts" json:"max_fix_attempts"`
+	// MaxRepairAttempts is how many times the model is asked again for a response that could not be parsed
+	MaxRepairAttempts int `mapstructure:"max_repair_attempts" json:"max_repair_attempts"`
+	// StructuredOutput constrains responses on backends that support it: schema, json or off
+	StructuredOutput string `mapstructure:"structured_output" json:"structured_output"`
+	// ContextCollectors names the collectors whose facts about the environment are added to the system prompt
+	ContextCollectors []string `mapstructure:"context_collectors" json:"context_collectors"`
+	// Template names the system prompt template; empty or "default" selects the built-in prompt
+	Template string `mapstructure:"template" json:"template"`
+	// Language selects the language of the prompts and answers, such as "en" or "zh"
+	Language string `mapstructure:"language" json:"language"`
 }
 
 // DefaultConfig returns the default configuration
@@ -29,11 +58,20 @@ func DefaultConfig() *Config {
 	}
 
 	return &Config{
+		Provider:  "ollama",
 		Model:     "qwen3:14b",
 		ServerURL: "http://localhost:11434",
 		Token:     "",
 		LogLevel:  "info",
 		ConfigDir: filepath.Join(homeDir, ".autocmdr"),
+		Shell:     "",
+
+		MaxOutputBytes:    1 << 20,
+		MaxFixAttempts:    3,
+		MaxRepairAttempts: 2,
+		StructuredOutput:  "schema",
+		ContextCollectors: append([]string{}, sysinfo.Names...),
+		Language:          prompts.DefaultLanguage,
 	}
 }
 
@@ -52,11 +90,26 @@ func Load() (*Config, error) {
 	viper.AutomaticEnv()
 
 	// Set default values
+	viper.SetDefault("provider", cfg.Provider)
 	viper.SetDefault("model", cfg.Model)
 	viper.SetDefault("server_url", cfg.ServerURL)
 	viper.SetDefault("token", cfg.Token)
 	viper.SetDefault("log_level", cfg.LogLevel)
 	viper.SetDefault("config_dir", cfg.ConfigDir)
+	viper.SetDefault("shell", cfg.Shell)
+	viper.SetDefault("max_output_bytes", cfg.MaxOutputBytes)
+	viper.SetDefault("exec_timeout", cfg.ExecTimeout)
+	viper.SetDefault("limit_cpu_seconds", cfg.LimitCPUSeconds)
+	viper.SetDefault("limit_address_space_mb", cfg.LimitAddressSpaceMB)
+	viper.SetDefault("limit_open_files", cfg.LimitOpenFiles)
+	viper.SetDefault("limit_processes", cfg.LimitProcesses)
+	viper.SetDefault("auto_fix", cfg.AutoFix)
+	viper.SetDefault("max_fix_attempts", cfg.MaxFixAttempts)
+	viper.SetDefault("max_repair_attempts", cfg.MaxRepairAttempts)
+	viper.SetDefault("structured_output", cfg.StructuredOutput)
+	viper.SetDefault("context_collectors", cfg.ContextCollectors)
+	viper.SetDefault("template", cfg.Template)
+	viper.SetDefault("language", cfg.Language)
 
 	// Try to read config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -91,11 +144,26 @@ func (c *Config) Save() error {
 	}
 
 	// Set viper values
+	viper.Set("provider", c.Provider)
 	viper.Set("model", c.Model)
 	viper.Set("server_url", c.ServerURL)
 	viper.Set("token", c.Token)
 	viper.Set("log_level", c.LogLevel)
 	viper.Set("config_dir", c.ConfigDir)
+	viper.Set("shell", c.Shell)
+	viper.Set("max_output_bytes", c.MaxOutputBytes)
+	viper.Set("exec_timeout", c.ExecTimeout.String())
+	viper.Set("limit_cpu_seconds", c.LimitCPUSeconds)
+	viper.Set("limit_address_space_mb", c.LimitAddressSpaceMB)
+	viper.Set("limit_open_files", c.LimitOpenFiles)
+	viper.Set("limit_processes", c.LimitProcesses)
+	viper.Set("auto_fix", c.AutoFix)
+	viper.Set("max_fix_attempts", c.MaxFixAttempts)
+	viper.Set("max_repair_attempts", c.MaxRepairAttempts)
+	viper.Set("structured_output", c.StructuredOutput)
+	viper.Set("context_collectors", c.ContextCollectors)
+	viper.Set("template", c.Template)
+	viper.Set("language", c.Language)
 
 	// Write config file
 	if err := viper.WriteConfigAs(configPath); err != nil {
@@ -108,12 +176,29 @@ func (c *Config) Save() error {
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
+	if c.Provider == "" {
+		return fmt.Errorf("provider cannot be empty")
+	}
 	if c.Model == "" {
 		return fmt.Errorf("model cannot be empty")
 	}
 	if c.ServerURL == "" {
 		return fmt.Errorf("server_url cannot be empty")
 	}
+	if c.ExecTimeout < 0 {
+		return fmt.Errorf("exec_timeout cannot be negative")
+	}
+	if c.MaxFixAttempts < 0 {
+		return fmt.Errorf("max_fix_attempts cannot be negative")
+	}
+	if c.MaxRepairAttempts < 0 {
+		return fmt.Errorf("max_repair_attempts cannot be negative")
+	}
+	for _, name := range c.ContextCollectors {
+		if !sysinfo.Known(name) {
+			return fmt.Errorf("unknown context collector: %s (available: %s)", name, strings.Join(sysinfo.Names, ", "))
+		}
+	}
 	return nil
 }
 
@@ -5,11 +5,15 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 
+	if cfg.Provider != "ollama" {
+		t.Errorf("expected Provider to be 'ollama', got '%s'", cfg.Provider)
+	}
 	if cfg.Model == "" {
 		t.Error("expected Model to be non-empty")
 	}
@@ -39,11 +43,14 @@ func TestConfigSaveAndLoad(t *testing.T) {
 
 	// Create test config
 	cfg := &Config{
+		Provider:  "openai",
 		Model:     "test-model",
 		ServerURL: "http://test.example.com:11434",
 		Token:     "test-token",
 		LogLevel:  "debug",
 		ConfigDir: tempDir,
+
+		ExecTimeout: 30 * time.Second,
 	}
 
 	// Save config
@@ -77,6 +84,9 @@ func TestConfigSaveAndLoad(t *testing.T) {
 	}
 
 	// Compare configs
+	if cfg.Provider != loadedCfg.Provider {
+		t.Errorf("expected Provider %s, got %s", cfg.Provider, loadedCfg.Provider)
+	}
 	if cfg.Model != loadedCfg.Model {
 		t.Errorf("expected Model %s, got %s", cfg.Model, loadedCfg.Model)
 	}
@@ -89,6 +99,9 @@ func TestConfigSaveAndLoad(t *testing.T) {
 	if cfg.LogLevel != loadedCfg.LogLevel {
 		t.Errorf("expected LogLevel %s, got %s", cfg.LogLevel, loadedCfg.LogLevel)
 	}
+	if cfg.ExecTimeout != loadedCfg.ExecTimeout {
+		t.Errorf("expected ExecTimeout %s, got %s", cfg.ExecTimeout, loadedCfg.ExecTimeout)
+	}
 }
 
 func TestGetConfigPath(t *testing.T) {
@@ -0,0 +1,361 @@
+// Package eval runs a suite of natural-language tasks against a model and checks the scripts it generates,
+// so that models can be compared and prompt changes checked for regressions.
+package eval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blysin/autocmdr/pkg/chat"
+	"github.com/blysin/autocmdr/pkg/policy"
+	"github.com/blysin/autocmdr/pkg/risk"
+)
+
+// Suite is a list of tasks loaded from a YAML file
+type Suite struct {
+	Name  string `yaml:"name"`
+	Tasks []Task `yaml:"tasks"`
+}
+
+// Task is a request sent to the model and the assertions its response must pass
+type Task struct {
+	Name    string `yaml:"name"`
+	Request string `yaml:"request"`
+	Expect  Expect `yaml:"expect"`
+	// Run executes the script in a scratch directory when set
+	Run *Run `yaml:"run"`
+}
+
+// Expect holds the assertions on the generated script
+type Expect struct {
+	// Success is the expected success field; false expects the model to ask or refuse instead of giving a script
+	Success *bool `yaml:"success"`
+	// Commands are command names the script must run; alternatives are separated by "|", such as "ss|netstat"
+	Commands []string `yaml:"commands"`
+	// Forbid are regular expressions the script must not match
+	Forbid []string `yaml:"forbid"`
+	// MaxRisk is the highest risk level allowed: read-only, mutating, destructive or privileged
+	MaxRisk string `yaml:"max_risk"`
+}
+
+// Run describes how to execute the script in a scratch directory and the expected outcome
+type Run struct {
+	// Files are created in the scratch directory before the script runs, mapping relative paths to contents
+	Files map[string]string `yaml:"files"`
+	// Setup is a script run in the scratch directory before the generated one
+	Setup string `yaml:"setup"`
+	// ExitCode is the expected exit code of the script
+	ExitCode int `yaml:"exit_code"`
+	// Stdout lists text the standard output must contain
+	Stdout []string `yaml:"stdout"`
+	// Check is a script run in the scratch directory afterwards that must succeed, such as "test -f out.tar.gz"
+	Check string `yaml:"check"`
+}
+
+// LoadSuite reads and validates a suite file. A suite without a name is named after its file.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+	suite := &Suite{}
+	if err := yaml.Unmarshal(data, suite); err != nil {
+		return nil, fmt.Errorf("failed to parse suite %s: %w", path, err)
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := suite.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid suite %s: %w", path, err)
+	}
+	return suite, nil
+}
+
+// Validate checks that every task has a unique name, a request and valid assertions
+func (s *Suite) Validate() error {
+	if len(s.Tasks) == 0 {
+		return fmt.Errorf("no tasks")
+	}
+	names := make(map[string]bool)
+	for i, task := range s.Tasks {
+		if task.Name == "" {
+			return fmt.Errorf("task %d has no name", i+1)
+		}
+		if names[task.Name] {
+			return fmt.Errorf("duplicate task name: %s", task.Name)
+		}
+		names[task.Name] = true
+		if strings.TrimSpace(task.Request) == "" {
+			return fmt.Errorf("task %s has no request", task.Name)
+		}
+		for _, pattern := range task.Expect.Forbid {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("task %s has an invalid forbid pattern: %w", task.Name, err)
+			}
+		}
+		if task.Expect.MaxRisk != "" {
+			if _, err := parseLevel(task.Expect.MaxRisk); err != nil {
+				return fmt.Errorf("task %s: %w", task.Name, err)
+			}
+		}
+		if task.Run != nil {
+			for path := range task.Run.Files {
+				if !filepath.IsLocal(path) {
+					return fmt.Errorf("task %s creates a file outside the scratch directory: %s", task.Name, path)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Generator sends a request to the model and returns its parsed response
+type Generator func(ctx context.Context, request string) (*chat.AssistantResult, error)
+
+// ExecutorFactory creates an executor that runs scripts in dir
+type ExecutorFactory func(dir string) (chat.ScriptExecutor, error)
+
+// Result is the outcome of one task
+type Result struct {
+	Suite    string        `json:"suite"`
+	Task     string        `json:"task"`
+	Passed   bool          `json:"passed"`
+	Script   string        `json:"script,omitempty"`
+	Failures []string      `json:"failures,omitempty"`
+	Executed bool          `json:"executed"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Runner runs the tasks of suites
+type Runner struct {
+	generate    Generator
+	newExecutor ExecutorFactory
+	policy      *policy.Policy
+}
+
+// NewRunner creates a runner that asks generate for scripts. Scripts of tasks with a run section
+// are executed with executors from newExecutor; a nil newExecutor skips execution.
+func NewRunner(generate Generator, newExecutor ExecutorFactory) *Runner {
+	return &Runner{generate: generate, newExecutor: newExecutor}
+}
+
+// SetPolicy sets the policy that scripts are checked against before they are executed
+func (r *Runner) SetPolicy(p *policy.Policy) {
+	r.policy = p
+}
+
+// Run runs the tasks of a suite whose names match filter, or all tasks if filter is nil
+func (r *Runner) Run(ctx context.Context, suite *Suite, filter *regexp.Regexp) []Result {
+	var results []Result
+	for i := range suite.Tasks {
+		task := &suite.Tasks[i]
+		if filter != nil && !filter.MatchString(task.Name) {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		result := r.RunTask(ctx, task)
+		result.Suite = suite.Name
+		results = append(results, result)
+	}
+	return results
+}
+
+// RunTask sends the request of a task to the model and checks the response
+func (r *Runner) RunTask(ctx context.Context, task *Task) Result {
+	start := time.Now()
+	result := Result{Task: task.Name}
+
+	response, err := r.generate(ctx, task.Request)
+	if err != nil {
+		result.Failures = []string{fmt.Sprintf("no valid response: %v", err)}
+		result.Duration = time.Since(start)
+		return result
+	}
+	result.Script = script(response)
+	result.Failures = task.check(response)
+
+	if len(result.Failures) == 0 && task.Run != nil && response.Success && r.newExecutor != nil {
+		result.Executed = true
+		result.Failures = r.execute(ctx, task, result.Script)
+	}
+	result.Passed = len(result.Failures) == 0
+	result.Duration = time.Since(start)
+	return result
+}
+
+// check returns the assertions of the task that the response fails
+func (t *Task) check(response *chat.AssistantResult) []string {
+	wantSuccess := t.Expect.Success == nil || *t.Expect.Success
+	if response.Success != wantSuccess {
+		if wantSuccess {
+			return []string{fmt.Sprintf("expected a script, got: %s", response.Script)}
+		}
+		return []string{"expected the model to ask or refuse, got a script"}
+	}
+	if !response.Success {
+		return nil
+	}
+
+	text := script(response)
+	var failures []string
+	assessment := risk.Analyze(text)
+	for _, want := range t.Expect.Commands {
+		if !runsCommand(assessment, strings.Split(want, "|")) {
+			failures = append(failures, fmt.Sprintf("expected command %s", want))
+		}
+	}
+	for _, pattern := range t.Expect.Forbid {
+		if regexp.MustCompile(pattern).MatchString(text) {
+			failures = append(failures, fmt.Sprintf("forbidden pattern %q matched", pattern))
+		}
+	}
+	if t.Expect.MaxRisk != "" {
+		if limit, _ := parseLevel(t.Expect.MaxRisk); assessment.Level > limit {
+			failures = append(failures, fmt.Sprintf("risk %s exceeds %s", assessment.Level, limit))
+		}
+	}
+	return failures
+}
+
+// execute runs the script in a scratch directory and returns the failed expectations of the run section.
+// Only read-only scripts run unless the task allows more with max_risk, privileged ones never run,
+// and scripts denied by the policy do not run either, since they can still reach outside the scratch directory.
+func (r *Runner) execute(ctx context.Context, task *Task, text string) []string {
+	allowed := risk.ReadOnly
+	if task.Expect.MaxRisk != "" {
+		allowed, _ = parseLevel(task.Expect.MaxRisk)
+	}
+	if level := risk.Analyze(text).Level; level > allowed || level == risk.Privileged {
+		return []string{fmt.Sprintf("not executed: the script is %s", level)}
+	}
+	if r.policy != nil {
+		if _, err := r.policy.Check(text); err != nil {
+			return []string{fmt.Sprintf("not executed: %v", err)}
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "autocmdr-eval-")
+	if err != nil {
+		return []string{fmt.Sprintf("failed to create scratch directory: %v", err)}
+	}
+	defer os.RemoveAll(dir)
+
+	for path, content := range task.Run.Files {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o750); err != nil {
+			return []string{fmt.Sprintf("failed to create %s: %v", path, err)}
+		}
+		if err := os.WriteFile(full, []byte(content), 0o600); err != nil {
+			return []string{fmt.Sprintf("failed to create %s: %v", path, err)}
+		}
+	}
+
+	executor, err := r.newExecutor(dir)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to create executor: %v", err)}
+	}
+	if task.Run.Setup != "" {
+		if setup, err := executor.Execute(ctx, task.Run.Setup); err != nil || !setup.Success {
+			return []string{fmt.Sprintf("setup failed: %s", outcome(setup, err))}
+		}
+	}
+
+	run, err := executor.Execute(ctx, text)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to execute script: %v", err)}
+	}
+	var failures []string
+	if run.ExitCode != task.Run.ExitCode {
+		failures = append(failures, fmt.Sprintf("expected exit code %d, got %s", task.Run.ExitCode, outcome(run, nil)))
+	}
+	for _, want := range task.Run.Stdout {
+		if !strings.Contains(run.Stdout, want) {
+			failures = append(failures, fmt.Sprintf("expected %q in the output", want))
+		}
+	}
+	if task.Run.Check != "" {
+		if check, err := executor.Execute(ctx, task.Run.Check); err != nil || !check.Success {
+			failures = append(failures, fmt.Sprintf("check %q failed: %s", task.Run.Check, outcome(check, err)))
+		}
+	}
+	return failures
+}
+
+// script returns the script of a response, joining the steps of a plan
+func script(response *chat.AssistantResult) string {
+	if len(response.Steps) == 0 {
+		return response.Script
+	}
+	scripts := make([]string, len(response.Steps))
+	for i, step := range response.Steps {
+		scripts[i] = step.Script
+	}
+	return strings.Join(scripts, "\n")
+}
+
+// runsCommand reports whether the assessed script runs one of the named commands
+func runsCommand(assessment *risk.Assessment, names []string) bool {
+	for _, command := range assessment.Commands {
+		for _, name := range names {
+			if command.Name == strings.TrimSpace(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseLevel returns the risk level with the given name
+func parseLevel(name string) (risk.Level, error) {
+	for level := risk.ReadOnly; level <= risk.Privileged; level++ {
+		if level.String() == name {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown risk level: %s (use read-only, mutating, destructive or privileged)", name)
+}
+
+// outcome describes why a setup or check script failed
+func outcome(result *chat.ExecutionResult, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if stderr := strings.TrimSpace(result.Stderr); stderr != "" {
+		return fmt.Sprintf("exit code %d: %s", result.ExitCode, stderr)
+	}
+	return fmt.Sprintf("exit code %d", result.ExitCode)
+}
+
+// Report summarizes the results of an evaluation
+type Report struct {
+	Model   string   `json:"model"`
+	Results []Result `json:"results"`
+}
+
+// Passed returns the number of tasks that passed
+func (r *Report) Passed() int {
+	passed := 0
+	for _, result := range r.Results {
+		if result.Passed {
+			passed++
+		}
+	}
+	return passed
+}
+
+// PassRate returns the share of tasks that passed, from 0 to 1
+func (r *Report) PassRate() float64 {
+	if len(r.Results) == 0 {
+		return 0
+	}
+	return float64(r.Passed()) / float64(len(r.Results))
+}
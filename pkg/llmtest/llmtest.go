@@ -0,0 +1,327 @@
+// Package llmtest provides a fake llms.Model that replays recorded model responses from cassette files,
+// so that code talking to a model can be tested offline and deterministically.
+// In record mode it wraps a real model and writes the requests and responses it sees to a cassette.
+package llmtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"gopkg.in/yaml.v3"
+)
+
+// RecordEnv is the environment variable that makes Open record new cassettes with a real model
+const RecordEnv = "AUTOCMDR_RECORD"
+
+// Message is one message of a recorded request
+type Message struct {
+	Role string `yaml:"role"`
+	Text string `yaml:"text"`
+}
+
+// ToolCall is a tool call in a recorded response
+type ToolCall struct {
+	ID        string `yaml:"id"`
+	Name      string `yaml:"name"`
+	Arguments string `yaml:"arguments"`
+}
+
+// Interaction is a recorded request and the model's response to it
+type Interaction struct {
+	Request   []Message  `yaml:"request"`
+	Response  string     `yaml:"response"`
+	ToolCalls []ToolCall `yaml:"tool_calls,omitempty"`
+}
+
+// Cassette is the list of interactions recorded in one file
+type Cassette struct {
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	cassette := &Cassette{}
+	if err := yaml.Unmarshal(data, cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return cassette, nil
+}
+
+// Save writes the cassette to a file, creating its directory if needed
+func (c *Cassette) Save(path string) error {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// Matcher reports whether a request matches a recorded one
+type Matcher func(recorded, actual []Message) bool
+
+// MatchExact matches requests with the same messages
+func MatchExact(recorded, actual []Message) bool {
+	return reflect.DeepEqual(recorded, actual)
+}
+
+// MatchIgnoring matches requests whose messages are the same once the patterns are removed from their text.
+// Use it for parts of a prompt that vary between machines, such as the operating system version.
+func MatchIgnoring(patterns ...*regexp.Regexp) Matcher {
+	strip := func(messages []Message) []Message {
+		stripped := make([]Message, len(messages))
+		for i, message := range messages {
+			for _, pattern := range patterns {
+				message.Text = pattern.ReplaceAllString(message.Text, "")
+			}
+			stripped[i] = message
+		}
+		return stripped
+	}
+	return func(recorded, actual []Message) bool {
+		return reflect.DeepEqual(strip(recorded), strip(actual))
+	}
+}
+
+// Model is an llms.Model that replays a cassette, or records one while forwarding requests to a real model
+type Model struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+	matcher  Matcher
+	misses   [][]Message
+	// llm is the real model requests are forwarded to in record mode
+	llm llms.Model
+}
+
+var _ llms.Model = (*Model)(nil)
+
+// NewReplayer creates a model that answers each request with the first unused recorded interaction it matches.
+// A nil matcher uses MatchExact.
+func NewReplayer(cassette *Cassette, matcher Matcher) *Model {
+	if matcher == nil {
+		matcher = MatchExact
+	}
+	return &Model{
+		cassette: cassette,
+		used:     make([]bool, len(cassette.Interactions)),
+		matcher:  matcher,
+	}
+}
+
+// NewRecorder creates a model that forwards requests to llm and records them with its responses
+func NewRecorder(llm llms.Model) *Model {
+	return &Model{cassette: &Cassette{}, llm: llm}
+}
+
+// Cassette returns the replayed or recorded cassette
+func (m *Model) Cassette() *Cassette {
+	return m.cassette
+}
+
+// Recording reports whether the model records a cassette rather than replaying one
+func (m *Model) Recording() bool {
+	return m.llm != nil
+}
+
+// Unused returns the recorded interactions that have not been replayed yet
+func (m *Model) Unused() []Interaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var unused []Interaction
+	for i, used := range m.used {
+		if !used {
+			unused = append(unused, m.cassette.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// Misses returns the requests that had no recorded response
+func (m *Model) Misses() [][]Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]Message(nil), m.misses...)
+}
+
+// Call sends a single prompt to the model
+func (m *Model) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// GenerateContent replays the response recorded for the messages, or records the response of the real model
+func (m *Model) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	request := requestMessages(messages)
+	if m.llm != nil {
+		return m.record(ctx, request, messages, options)
+	}
+
+	interaction, err := m.replay(request)
+	if err != nil {
+		return nil, err
+	}
+	opts := llms.CallOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.StreamingFunc != nil && interaction.Response != "" {
+		if err := opts.StreamingFunc(ctx, []byte(interaction.Response)); err != nil {
+			return nil, err
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{responseChoice(interaction)}}, nil
+}
+
+// replay returns the first unused interaction matching the request
+func (m *Model) replay(request []Message) (*Interaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.cassette.Interactions {
+		if m.used[i] || !m.matcher(m.cassette.Interactions[i].Request, request) {
+			continue
+		}
+		m.used[i] = true
+		return &m.cassette.Interactions[i], nil
+	}
+	m.misses = append(m.misses, request)
+	return nil, fmt.Errorf("no recorded response for the request (%d messages, last: %q)", len(request), lastText(request))
+}
+
+// record forwards the request to the real model and records its response
+func (m *Model) record(ctx context.Context, request []Message, messages []llms.MessageContent, options []llms.CallOption) (*llms.ContentResponse, error) {
+	resp, err := m.llm.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return resp, nil
+	}
+
+	choice := resp.Choices[0]
+	interaction := Interaction{Request: request, Response: choice.Content}
+	for _, call := range choice.ToolCalls {
+		if call.FunctionCall == nil {
+			continue
+		}
+		interaction.ToolCalls = append(interaction.ToolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.FunctionCall.Name,
+			Arguments: call.FunctionCall.Arguments,
+		})
+	}
+
+	m.mu.Lock()
+	m.cassette.Interactions = append(m.cassette.Interactions, interaction)
+	m.mu.Unlock()
+	return resp, nil
+}
+
+// Open returns a model for a test that replays the cassette at path. The test fails if a request has no
+// recorded response or recorded interactions are left unused. When RecordEnv is set, the model instead
+// forwards requests to the model created by newModel and writes them to path when the test ends.
+func Open(t testing.TB, path string, matcher Matcher, newModel func() (llms.Model, error)) *Model {
+	t.Helper()
+
+	if os.Getenv(RecordEnv) != "" {
+		llm, err := newModel()
+		if err != nil {
+			t.Fatalf("failed to create the model to record with: %v", err)
+		}
+		m := NewRecorder(llm)
+		t.Cleanup(func() {
+			if t.Failed() {
+				t.Logf("not writing cassette %s because the test failed", path)
+				return
+			}
+			if err := m.Cassette().Save(path); err != nil {
+				t.Error(err)
+			}
+		})
+		return m
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("%v (set %s=1 to record it)", err, RecordEnv)
+	}
+	m := NewReplayer(cassette, matcher)
+	t.Cleanup(func() {
+		for _, request := range m.Misses() {
+			t.Errorf("cassette %s has no response for a request ending with %q (set %s=1 to record it again)", path, lastText(request), RecordEnv)
+		}
+		if unused := m.Unused(); len(unused) > 0 {
+			t.Errorf("cassette %s has %d unused interactions", path, len(unused))
+		}
+	})
+	return m
+}
+
+// requestMessages converts messages to their recorded form
+func requestMessages(messages []llms.MessageContent) []Message {
+	request := make([]Message, 0, len(messages))
+	for _, message := range messages {
+		parts := make([]string, 0, len(message.Parts))
+		for _, part := range message.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				parts = append(parts, p.Text)
+			case llms.ToolCall:
+				if p.FunctionCall != nil {
+					parts = append(parts, fmt.Sprintf("[tool call %s] %s %s", p.ID, p.FunctionCall.Name, p.FunctionCall.Arguments))
+				}
+			case llms.ToolCallResponse:
+				parts = append(parts, fmt.Sprintf("[tool response %s] %s", p.ToolCallID, p.Content))
+			default:
+				parts = append(parts, fmt.Sprintf("[%T]", p))
+			}
+		}
+		request = append(request, Message{Role: string(message.Role), Text: strings.Join(parts, "\n")})
+	}
+	return request
+}
+
+// responseChoice converts a recorded interaction to the choice the model returns
+func responseChoice(interaction *Interaction) *llms.ContentChoice {
+	choice := &llms.ContentChoice{Content: interaction.Response}
+	for _, call := range interaction.ToolCalls {
+		choice.ToolCalls = append(choice.ToolCalls, llms.ToolCall{
+			ID:           call.ID,
+			Type:         "function",
+			FunctionCall: &llms.FunctionCall{Name: call.Name, Arguments: call.Arguments},
+		})
+	}
+	return choice
+}
+
+// lastText returns the end of the last message of a request, for error messages
+func lastText(request []Message) string {
+	if len(request) == 0 {
+		return ""
+	}
+	text := []rune(request[len(request)-1].Text)
+	const maxLen = 200
+	if len(text) > maxLen {
+		return "..." + string(text[len(text)-maxLen:])
+	}
+	return string(text)
+}
@@ -0,0 +1,153 @@
+package llmtest
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/fake"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cassettes", "list.yaml")
+
+	recorder := NewRecorder(fake.NewFakeLLM([]string{"first\nanswer", "second answer"}))
+	if !recorder.Recording() {
+		t.Error("Recording() = false for a recorder")
+	}
+	for _, prompt := range []string{"list files", "count them"} {
+		if _, err := recorder.Call(ctx, prompt); err != nil {
+			t.Fatalf("Call(%q) error = %v", prompt, err)
+		}
+	}
+	if err := recorder.Cassette().Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Interaction{
+		{Request: []Message{{Role: "human", Text: "list files"}}, Response: "first\nanswer"},
+		{Request: []Message{{Role: "human", Text: "count them"}}, Response: "second answer"},
+	}
+	if !reflect.DeepEqual(cassette.Interactions, want) {
+		t.Fatalf("LoadCassette() = %+v, want %+v", cassette.Interactions, want)
+	}
+
+	replayer := NewReplayer(cassette, nil)
+	// Requests are matched by their messages, not by their order
+	for _, tt := range []struct{ prompt, want string }{{"count them", "second answer"}, {"list files", "first\nanswer"}} {
+		var streamed strings.Builder
+		got, err := replayer.Call(ctx, tt.prompt, llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			streamed.Write(chunk)
+			return nil
+		}))
+		if err != nil || got != tt.want {
+			t.Errorf("Call(%q) = %q, %v, want %q", tt.prompt, got, err, tt.want)
+		}
+		if streamed.String() != tt.want {
+			t.Errorf("Call(%q) streamed %q, want %q", tt.prompt, streamed.String(), tt.want)
+		}
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("Unused() = %+v, want none", unused)
+	}
+
+	if _, err := replayer.Call(ctx, "list files"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("Call() of a replayed request error = %v, want no recorded response", err)
+	}
+	if misses := replayer.Misses(); len(misses) != 1 || misses[0][0].Text != "list files" {
+		t.Errorf("Misses() = %+v, want the replayed request", misses)
+	}
+}
+
+func TestReplayToolCalls(t *testing.T) {
+	cassette := &Cassette{Interactions: []Interaction{{
+		Request: []Message{
+			{Role: "system", Text: "inspect first"},
+			{Role: "human", Text: "build it"},
+			{Role: "ai", Text: "[tool call call-1] read_file_head {\"path\":\"Makefile\"}"},
+			{Role: "tool", Text: "[tool response call-1] build:"},
+		},
+		Response: `{"success": true, "script": "make build"}`,
+	}, {
+		Request:   []Message{{Role: "system", Text: "inspect first"}, {Role: "human", Text: "build it"}},
+		ToolCalls: []ToolCall{{ID: "call-1", Name: "read_file_head", Arguments: `{"path":"Makefile"}`}},
+	}}}
+	replayer := NewReplayer(cassette, nil)
+	ctx := context.Background()
+
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "inspect first"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "build it"),
+	}
+	resp, err := replayer.GenerateContent(ctx, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := resp.Choices[0].ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call-1" || calls[0].FunctionCall.Name != "read_file_head" {
+		t.Fatalf("GenerateContent() tool calls = %+v, want the recorded call", calls)
+	}
+
+	messages = append(messages,
+		llms.MessageContent{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{calls[0]}},
+		llms.MessageContent{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{
+			llms.ToolCallResponse{ToolCallID: "call-1", Name: "read_file_head", Content: "build:"},
+		}},
+	)
+	resp, err = replayer.GenerateContent(ctx, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Choices[0].Content; got != `{"success": true, "script": "make build"}` {
+		t.Errorf("GenerateContent() = %q, want the final answer", got)
+	}
+}
+
+func TestMatchIgnoring(t *testing.T) {
+	matcher := MatchIgnoring(regexp.MustCompile(`OS: [^\n]*`))
+	recorded := []Message{{Role: "human", Text: "OS: Linux 6.1\nlist files"}}
+
+	tests := []struct {
+		name   string
+		actual []Message
+		want   bool
+	}{
+		{name: "same", actual: recorded, want: true},
+		{name: "other OS", actual: []Message{{Role: "human", Text: "OS: Darwin 23\nlist files"}}, want: true},
+		{name: "other request", actual: []Message{{Role: "human", Text: "OS: Linux 6.1\ndelete files"}}, want: false},
+		{name: "other role", actual: []Message{{Role: "system", Text: "OS: Linux 6.1\nlist files"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matcher(recorded, tt.actual); got != tt.want {
+				t.Errorf("matcher() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenReplaysCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.yaml")
+	cassette := &Cassette{Interactions: []Interaction{{Request: []Message{{Role: "human", Text: "hello"}}, Response: "hi"}}}
+	if err := cassette.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(RecordEnv, "")
+
+	model := Open(t, path, nil, func() (llms.Model, error) {
+		t.Fatal("the real model is only created when recording")
+		return nil, nil
+	})
+	if got, err := model.Call(context.Background(), "hello"); err != nil || got != "hi" {
+		t.Errorf("Call() = %q, %v, want hi", got, err)
+	}
+}
@@ -0,0 +1,400 @@
+// Package policy enforces user-defined allow and deny rules on generated scripts before they are executed.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blysin/autocmdr/pkg/risk"
+)
+
+// FileName is the name of the policy file in the config and project directories
+const FileName = "policy.yaml"
+
+// Default actions for commands that match no rule
+const (
+	DefaultAllow = "allow"
+	DefaultDeny  = "deny"
+)
+
+// Action is the outcome of evaluating a script against a policy
+type Action int
+
+// Policy actions
+const (
+	// Confirm means the script may run after the user confirms it
+	Confirm Action = iota
+	// AutoApprove means the script is read-only and fully allow-listed, so it may run without confirmation
+	AutoApprove
+	// Deny means the script must not run
+	Deny
+)
+
+// String returns the display name of the action
+func (a Action) String() string {
+	switch a {
+	case Confirm:
+		return "confirm"
+	case AutoApprove:
+		return "auto-approve"
+	case Deny:
+		return "deny"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Rule matches commands by name, arguments, paths and environment.
+// All conditions that are set must match for the rule to apply.
+type Rule struct {
+	Name    string   `yaml:"name"`
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	Paths   []string `yaml:"paths"`
+	Env     []string `yaml:"env"`
+	Reason  string   `yaml:"reason"`
+}
+
+// Policy is a set of allow and deny rules
+type Policy struct {
+	Default string `yaml:"default"`
+	Allow   []Rule `yaml:"allow"`
+	Deny    []Rule `yaml:"deny"`
+
+	// Sources lists the files the policy was loaded from
+	Sources []string `yaml:"-"`
+
+	lookupEnv func(string) (string, bool)
+	workDir   string
+}
+
+// Decision is the result of evaluating a script
+type Decision struct {
+	Action  Action
+	Rule    *Rule
+	Command string
+}
+
+// DeniedError is returned when a script is refused by a deny rule
+type DeniedError struct {
+	Rule    Rule
+	Command string
+}
+
+// Error implements the error interface
+func (e *DeniedError) Error() string {
+	msg := fmt.Sprintf("script denied by policy rule %q", e.Rule.String())
+	if e.Command != "" {
+		msg += fmt.Sprintf(" (command: %s)", e.Command)
+	}
+	if e.Rule.Reason != "" {
+		msg += ": " + e.Rule.Reason
+	}
+	return msg
+}
+
+// String returns the rule name, or a description of its conditions if it has none
+func (r Rule) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	var parts []string
+	if r.Command != "" {
+		parts = append(parts, "command="+r.Command)
+	}
+	if len(r.Args) > 0 {
+		parts = append(parts, "args="+strings.Join(r.Args, ","))
+	}
+	if len(r.Paths) > 0 {
+		parts = append(parts, "paths="+strings.Join(r.Paths, ","))
+	}
+	if len(r.Env) > 0 {
+		parts = append(parts, "env="+strings.Join(r.Env, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// GlobalPath returns the path of the user-wide policy file
+func GlobalPath(configDir string) string {
+	return filepath.Join(configDir, FileName)
+}
+
+// ProjectPath returns the path of the per-project policy file
+func ProjectPath(workDir string) string {
+	return filepath.Join(workDir, ".autocmdr", FileName)
+}
+
+// New creates an empty policy that asks for confirmation of every script
+func New() *Policy {
+	return &Policy{
+		Default:   DefaultAllow,
+		lookupEnv: os.LookupEnv,
+	}
+}
+
+// Load loads the global policy from configDir and merges the project policy from workDir.
+// Missing files are ignored. A project policy can add rules and switch the default to deny,
+// but cannot relax the global policy.
+func Load(configDir, workDir string) (*Policy, error) {
+	p := New()
+	p.workDir = workDir
+
+	for _, path := range []string{GlobalPath(configDir), ProjectPath(workDir)} {
+		loaded, err := LoadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.merge(loaded)
+		p.Sources = append(p.Sources, path)
+	}
+
+	return p, nil
+}
+
+// LoadFile loads a single policy file
+func LoadFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := New()
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate checks that the policy is well formed
+func (p *Policy) Validate() error {
+	switch p.Default {
+	case "", DefaultAllow, DefaultDeny:
+	default:
+		return fmt.Errorf("default must be %q or %q, got %q", DefaultAllow, DefaultDeny, p.Default)
+	}
+	for _, rules := range [][]Rule{p.Allow, p.Deny} {
+		for _, rule := range rules {
+			if rule.Command == "" && len(rule.Args) == 0 && len(rule.Paths) == 0 {
+				return fmt.Errorf("rule %q must set command, args or paths", rule.String())
+			}
+		}
+	}
+	return nil
+}
+
+// merge adds the rules of other to p
+func (p *Policy) merge(other *Policy) {
+	if other.Default == DefaultDeny {
+		p.Default = DefaultDeny
+	}
+	p.Allow = append(p.Allow, other.Allow...)
+	p.Deny = append(p.Deny, other.Deny...)
+}
+
+// Evaluate checks an analyzed script against the policy.
+// A script without commands, such as one that could not be parsed, is never auto-approved
+// and is denied when the default is deny, since its commands cannot be checked against the rules.
+// Otherwise, the deny rules are matched against every word of a script that could not be parsed,
+// since the shell may run its first lines before it reaches the syntax error.
+func (p *Policy) Evaluate(assessment *risk.Assessment) Decision {
+	if len(assessment.Commands) == 0 && p.Default == DefaultDeny {
+		return Decision{
+			Action:  Deny,
+			Rule:    &Rule{Name: "default", Reason: "the commands of the script could not be determined"},
+			Command: assessment.FirstWord,
+		}
+	}
+	for _, cmd := range assessment.Guesses {
+		paths := p.pathArgs(cmd.Args)
+		for i := range p.Deny {
+			if p.matches(&p.Deny[i], cmd, paths) {
+				return Decision{Action: Deny, Rule: &p.Deny[i], Command: cmd.Name}
+			}
+		}
+	}
+
+	allowed := true
+
+	for _, cmd := range assessment.Commands {
+		paths := p.pathArgs(cmd.Args)
+		for i := range p.Deny {
+			if p.matches(&p.Deny[i], cmd, paths) {
+				return Decision{Action: Deny, Rule: &p.Deny[i], Command: cmd.Name}
+			}
+		}
+
+		if !p.allowedCommand(cmd, paths) {
+			allowed = false
+			if p.Default == DefaultDeny {
+				return Decision{
+					Action:  Deny,
+					Rule:    &Rule{Name: "default", Reason: "command is not in the allow list"},
+					Command: cmd.Name,
+				}
+			}
+		}
+	}
+
+	redirects := p.resolvePaths(assessment.Redirects)
+	for i := range p.Deny {
+		rule := &p.Deny[i]
+		if rule.Command == "" && len(rule.Args) == 0 && p.envMatches(rule) && anyPathMatches(rule.Paths, redirects) {
+			return Decision{Action: Deny, Rule: rule}
+		}
+	}
+
+	if allowed && len(assessment.Commands) > 0 && assessment.Level == risk.ReadOnly && len(p.Allow) > 0 {
+		return Decision{Action: AutoApprove}
+	}
+	return Decision{Action: Confirm}
+}
+
+// Check analyzes a script and returns a DeniedError if the policy refuses it
+func (p *Policy) Check(script string) (Decision, error) {
+	decision := p.Evaluate(risk.Analyze(script))
+	if decision.Action == Deny {
+		return decision, &DeniedError{Rule: *decision.Rule, Command: decision.Command}
+	}
+	return decision, nil
+}
+
+// allowedCommand reports whether a command matches an allow rule
+func (p *Policy) allowedCommand(cmd risk.Command, paths []string) bool {
+	for i := range p.Allow {
+		if p.matches(&p.Allow[i], cmd, paths) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether all conditions of a rule match a command
+func (p *Policy) matches(rule *Rule, cmd risk.Command, paths []string) bool {
+	if rule.Command != "" && !globMatch(strings.ToLower(rule.Command), cmd.Name) {
+		return false
+	}
+	if len(rule.Args) > 0 && !anyArgMatches(rule.Args, cmd.Args) {
+		return false
+	}
+	if len(rule.Paths) > 0 && !anyPathMatches(rule.Paths, paths) {
+		return false
+	}
+	return p.envMatches(rule)
+}
+
+// envMatches reports whether every env condition of the rule holds.
+// A condition is either NAME, which must be set, or NAME=glob.
+func (p *Policy) envMatches(rule *Rule) bool {
+	lookupEnv := p.lookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	for _, cond := range rule.Env {
+		name, pattern, hasValue := strings.Cut(cond, "=")
+		value, ok := lookupEnv(name)
+		if !ok {
+			return false
+		}
+		if hasValue && !globMatch(pattern, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// anyArgMatches reports whether any argument matches any pattern.
+// Single-letter flag patterns such as -r also match bundled flags such as -rf.
+func anyArgMatches(patterns, args []string) bool {
+	for _, pattern := range patterns {
+		for _, arg := range args {
+			if globMatch(pattern, arg) {
+				return true
+			}
+			if len(pattern) == 2 && pattern[0] == '-' && pattern[1] != '-' &&
+				len(arg) > 2 && arg[0] == '-' && arg[1] != '-' && strings.IndexByte(arg[1:], pattern[1]) != -1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// anyPathMatches reports whether any path matches any glob
+func anyPathMatches(globs, paths []string) bool {
+	for _, glob := range globs {
+		glob = expandHome(glob)
+		for _, path := range paths {
+			if pathMatch(glob, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathArgs returns the arguments of a command that are not flags, resolved to absolute paths
+func (p *Policy) pathArgs(args []string) []string {
+	var paths []string
+	for _, arg := range args {
+		if arg == "" || strings.HasPrefix(arg, "-") {
+			continue
+		}
+		paths = append(paths, arg)
+	}
+	return p.resolvePaths(paths)
+}
+
+// resolvePaths expands ~ and makes paths absolute relative to the working directory
+func (p *Policy) resolvePaths(paths []string) []string {
+	resolved := make([]string, 0, len(paths))
+	for _, path := range paths {
+		path = expandHome(path)
+		if !filepath.IsAbs(path) && p.workDir != "" {
+			path = filepath.Join(p.workDir, path)
+		}
+		resolved = append(resolved, filepath.Clean(path))
+	}
+	return resolved
+}
+
+// pathMatch matches a path against a glob where a trailing /** matches everything below a directory
+func pathMatch(glob, path string) bool {
+	if dir, ok := strings.CutSuffix(glob, "/**"); ok {
+		dir = filepath.Clean(dir)
+		return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+	}
+	return globMatch(filepath.Clean(glob), path)
+}
+
+// globMatch matches a value against a shell glob, treating malformed patterns as literals
+func globMatch(pattern, value string) bool {
+	matched, err := filepath.Match(pattern, value)
+	if err != nil {
+		return pattern == value
+	}
+	return matched
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,196 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blysin/autocmdr/pkg/risk"
+)
+
+func testPolicy(env map[string]string) *Policy {
+	p := New()
+	p.workDir = "/work"
+	p.lookupEnv = func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	p.Allow = []Rule{
+		{Command: "ls"},
+		{Command: "git", Args: []string{"status", "log", "diff"}},
+	}
+	p.Deny = []Rule{
+		{Name: "no-recursive-delete", Command: "rm", Args: []string{"-r", "--recursive"}, Reason: "use the trash"},
+		{Name: "protect-etc", Paths: []string{"/etc/**"}},
+		{Name: "no-prod-kubectl", Command: "kubectl", Env: []string{"KUBE_ENV=prod*"}},
+	}
+	return p
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		env      map[string]string
+		expected Action
+		rule     string
+	}{
+		{name: "allow-listed read-only command", script: "ls -la", expected: AutoApprove},
+		{name: "allow-listed git subcommand", script: "git status && ls", expected: AutoApprove},
+		{name: "command not in allow list", script: "cat README.md", expected: Confirm},
+		{name: "mutating git subcommand", script: "git commit -m wip", expected: Confirm},
+		{name: "bundled recursive flag", script: "rm -rf build", expected: Deny, rule: "no-recursive-delete"},
+		{name: "long recursive flag", script: "rm --recursive build", expected: Deny, rule: "no-recursive-delete"},
+		{name: "non-recursive delete", script: "rm build.log", expected: Confirm},
+		{name: "wrapped by sudo", script: "sudo rm -r /srv/data", expected: Deny, rule: "no-recursive-delete"},
+		{name: "path argument", script: "cat /etc/shadow", expected: Deny, rule: "protect-etc"},
+		{name: "relative path argument", script: "cat ../etc/hosts", expected: Deny, rule: "protect-etc"},
+		{name: "redirect target", script: "echo x > /etc/hosts", expected: Deny, rule: "protect-etc"},
+		{name: "env matches", script: "kubectl delete pod x", env: map[string]string{"KUBE_ENV": "production"}, expected: Deny, rule: "no-prod-kubectl"},
+		{name: "env does not match", script: "kubectl delete pod x", env: map[string]string{"KUBE_ENV": "staging"}, expected: Confirm},
+		{name: "env not set", script: "kubectl get pods", expected: Confirm},
+		{name: "script that cannot be parsed", script: "touch /tmp/x\nfi", expected: Confirm},
+		{name: "denied command in fish script", script: "rm -rf /tmp/x\nif true; echo a; end", expected: Deny, rule: "no-recursive-delete"},
+		{name: "denied command before parse error", script: "rm -r /tmp/x\n(", expected: Deny, rule: "no-recursive-delete"},
+		{name: "denied command after keyword", script: "if not rm -r /tmp/x; echo a; end", expected: Deny, rule: "no-recursive-delete"},
+		{name: "denied path in script that cannot be parsed", script: "cat /etc/shadow\nend", expected: Deny, rule: "protect-etc"},
+		{name: "parse error in allow-listed command", script: "ls (", expected: Confirm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := testPolicy(tt.env).Evaluate(risk.Analyze(tt.script))
+
+			if decision.Action != tt.expected {
+				t.Fatalf("expected action %s but got %s", tt.expected, decision.Action)
+			}
+			if tt.rule != "" && (decision.Rule == nil || decision.Rule.String() != tt.rule) {
+				t.Errorf("expected rule %q but got %+v", tt.rule, decision.Rule)
+			}
+		})
+	}
+}
+
+func TestEvaluateDefaultDeny(t *testing.T) {
+	p := testPolicy(nil)
+	p.Default = DefaultDeny
+
+	if decision := p.Evaluate(risk.Analyze("ls && git log")); decision.Action != AutoApprove {
+		t.Errorf("expected %s but got %s", AutoApprove, decision.Action)
+	}
+	if decision := p.Evaluate(risk.Analyze("ls && touch x")); decision.Action != Deny {
+		t.Errorf("expected %s but got %s", Deny, decision.Action)
+	}
+	for _, script := range []string{"rm -rf /tmp/x\nfi", "ls (", "x=1"} {
+		if decision := p.Evaluate(risk.Analyze(script)); decision.Action != Deny {
+			t.Errorf("expected %s for %q but got %s", Deny, script, decision.Action)
+		}
+	}
+}
+
+func TestEvaluateDeniesUnparsedScript(t *testing.T) {
+	p := New()
+	p.Deny = []Rule{{Name: "no-rm", Command: "rm"}}
+	for _, script := range []string{"rm -f /tmp/x\nif true; echo a; end", "rm -f /tmp/x\n(", "echo a; and /bin/rm x\nend"} {
+		if decision := p.Evaluate(risk.Analyze(script)); decision.Action != Deny || decision.Command != "rm" {
+			t.Errorf("expected %s of rm for %q but got %s of %q", Deny, script, decision.Action, decision.Command)
+		}
+	}
+	if decision := p.Evaluate(risk.Analyze("echo a\nend")); decision.Action != Confirm {
+		t.Errorf("expected %s but got %s", Confirm, decision.Action)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	_, err := testPolicy(nil).Check("rm -rf /tmp/x")
+
+	var denied *DeniedError
+	if !errors.As(err, &denied) {
+		t.Fatalf("expected DeniedError but got %v", err)
+	}
+	if denied.Rule.Name != "no-recursive-delete" || denied.Command != "rm" {
+		t.Errorf("unexpected denial: %+v", denied)
+	}
+
+	if _, err := testPolicy(nil).Check("ls"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	configDir := t.TempDir()
+	workDir := t.TempDir()
+
+	global := `
+allow:
+  - command: ls
+deny:
+  - name: no-dd
+    command: dd
+`
+	project := `
+default: deny
+deny:
+  - name: no-docker-prune
+    command: docker
+    args: [prune, system]
+`
+	if err := os.WriteFile(GlobalPath(configDir), []byte(global), 0o600); err != nil {
+		t.Fatalf("failed to write global policy: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(ProjectPath(workDir)), 0o750); err != nil {
+		t.Fatalf("failed to create project directory: %v", err)
+	}
+	if err := os.WriteFile(ProjectPath(workDir), []byte(project), 0o600); err != nil {
+		t.Fatalf("failed to write project policy: %v", err)
+	}
+
+	p, err := Load(configDir, workDir)
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+
+	if p.Default != DefaultDeny {
+		t.Errorf("expected default %q but got %q", DefaultDeny, p.Default)
+	}
+	if len(p.Allow) != 1 || len(p.Deny) != 2 {
+		t.Errorf("expected 1 allow and 2 deny rules but got %d and %d", len(p.Allow), len(p.Deny))
+	}
+	if len(p.Sources) != 2 {
+		t.Errorf("expected 2 sources but got %v", p.Sources)
+	}
+}
+
+func TestLoadMissingFiles(t *testing.T) {
+	p, err := Load(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decision := p.Evaluate(risk.Analyze("ls")); decision.Action != Confirm {
+		t.Errorf("expected %s but got %s", Confirm, decision.Action)
+	}
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "bad default", content: "default: maybe\n"},
+		{name: "empty rule", content: "deny:\n  - name: nothing\n"},
+		{name: "malformed yaml", content: "deny: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write policy: %v", err)
+			}
+			if _, err := LoadFile(path); err == nil {
+				t.Error("expected error but got none")
+			}
+		})
+	}
+}
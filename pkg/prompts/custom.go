@@ -0,0 +1,208 @@
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// TemplateExt is the file extension of user-defined templates
+const TemplateExt = ".tmpl"
+
+// DefaultTemplate selects the built-in system prompt for the operating system
+const DefaultTemplate = "default"
+
+// TemplateData is the data user-defined templates are rendered with
+type TemplateData struct {
+	OS        string // runtime.GOOS, such as linux, darwin or windows
+	OSVersion string
+	Shell     string // shell that runs scripts, such as bash or powershell
+	Cwd       string
+	User      string
+	Language  string
+	// Environment lists the facts collected about the environment, one per line
+	Environment string
+	// OutputFormat describes the JSON response autocmdr expects; it is appended when a template does not use it
+	OutputFormat string
+}
+
+// TemplateDirs returns the directories searched for user-defined templates.
+// The project directory comes last, so that its templates replace user-wide ones with the same name.
+func TemplateDirs(configDir, workDir string) []string {
+	return []string{
+		filepath.Join(configDir, "prompts"),
+		filepath.Join(workDir, ".autocmdr", "prompts"),
+	}
+}
+
+// SetTemplateDirs sets the directories searched for user-defined templates, later ones taking precedence
+func (l *Loader) SetTemplateDirs(dirs ...string) {
+	l.templateDirs = dirs
+}
+
+// SetShell sets the shell that runs scripts. Without a selected template, pwsh and powershell select
+// the PowerShell prompt and other shells the shell prompt, which asks for scripts in that shell.
+// The shell is also passed to user-defined templates.
+func (l *Loader) SetShell(shell string) {
+	l.shell = shell
+}
+
+// SetTemplate selects the system prompt template by name: a built-in one, a user-defined one,
+// or DefaultTemplate for the built-in prompt of the operating system. User-defined templates are
+// parsed and rendered once, so that mistakes are reported here rather than when the prompt is loaded.
+func (l *Loader) SetTemplate(name string) error {
+	switch name {
+	case "", DefaultTemplate:
+		l.template, l.custom = "", nil
+		return nil
+	case "shell", "powershell":
+		l.template, l.custom = name, nil
+		return nil
+	case "explain":
+		return fmt.Errorf("the explain template cannot be used as the system prompt")
+	}
+
+	path, ok := l.userTemplates()[name]
+	if !ok {
+		return fmt.Errorf("unknown template: %s (available: %s)", name, strings.Join(l.SystemTemplates(), ", "))
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	if _, err := l.render(tmpl); err != nil {
+		return fmt.Errorf("failed to render template %s: %w", path, err)
+	}
+
+	l.template, l.custom = name, tmpl
+	return nil
+}
+
+// SystemTemplates returns the names of the templates that can be selected as the system prompt
+func (l *Loader) SystemTemplates() []string {
+	return append([]string{DefaultTemplate, "shell", "powershell"}, l.userTemplateNames()...)
+}
+
+// Template returns the name of the selected system prompt template
+func (l *Loader) Template() string {
+	if l.template == "" {
+		return DefaultTemplate
+	}
+	return l.template
+}
+
+// render executes a user-defined template, appending the output format if the template does not use it
+func (l *Loader) render(tmpl *template.Template) (string, error) {
+	data := l.templateData()
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	prompt := b.String()
+	if !usesField(tmpl, "OutputFormat") {
+		prompt = strings.TrimRight(prompt, "\n") + "\n\n" + data.OutputFormat + "\n"
+	}
+	return prompt, nil
+}
+
+// templateData returns the data templates are rendered with
+func (l *Loader) templateData() *TemplateData {
+	data := &TemplateData{
+		OS:           runtime.GOOS,
+		OSVersion:    l.osVersion,
+		Shell:        l.shell,
+		Language:     l.language,
+		Environment:  l.environment,
+		OutputFormat: outputFormat(l.builtinPrompt()),
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		data.Cwd = cwd
+	}
+	if current, err := user.Current(); err == nil {
+		data.User = current.Username
+	}
+	return data
+}
+
+// userTemplates maps the names of user-defined templates to their paths
+func (l *Loader) userTemplates() map[string]string {
+	templates := make(map[string]string)
+	for _, dir := range l.templateDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != TemplateExt {
+				continue
+			}
+			name := strings.TrimSuffix(entry.Name(), TemplateExt)
+			if isBuiltin(name) {
+				continue
+			}
+			templates[name] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return templates
+}
+
+// userTemplateNames returns the names of the user-defined templates in sorted order
+func (l *Loader) userTemplateNames() []string {
+	templates := l.userTemplates()
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isBuiltin reports whether name is reserved for a built-in template
+func isBuiltin(name string) bool {
+	switch name {
+	case DefaultTemplate, "shell", "powershell", "explain":
+		return true
+	}
+	return false
+}
+
+// usesField reports whether a template refers to the named field
+func usesField(tmpl *template.Template, field string) bool {
+	return tmpl.Tree != nil && strings.Contains(tmpl.Tree.Root.String(), "."+field)
+}
+
+// outputFormat returns the output format section of a built-in prompt
+func outputFormat(prompt string) string {
+	var b strings.Builder
+	inSection := false
+	for _, line := range strings.SplitAfter(prompt, "\n") {
+		if strings.HasPrefix(line, "## ") || strings.HasPrefix(line, "{{") {
+			if inSection {
+				break
+			}
+			inSection = strings.Contains(line, "Output Format")
+		}
+		if inSection {
+			b.WriteString(line)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// EscapeTemplate escapes template delimiters in s, so that a prompt containing them can be
+// embedded in a Go template and still render as written
+func EscapeTemplate(s string) string {
+	return templateEscaper.Replace(s)
+}
+
+var templateEscaper = strings.NewReplacer("{{", `{{"{{"}}`, "}}", `{{"}}"}}`)
@@ -0,0 +1,133 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	lcprompts "github.com/tmc/langchaingo/prompts"
+)
+
+func writeTemplate(t *testing.T, dir, name, text string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUserTemplates(t *testing.T) {
+	root := t.TempDir()
+	dirs := TemplateDirs(filepath.Join(root, "config"), filepath.Join(root, "project"))
+	writeTemplate(t, dirs[0], "k8s.tmpl", "user-wide k8s")
+	writeTemplate(t, dirs[0], "data.tmpl", "data engineering")
+	writeTemplate(t, dirs[0], "notes.txt", "not a template")
+	writeTemplate(t, dirs[0], "shell.tmpl", "cannot replace a built-in")
+	writeTemplate(t, dirs[1], "k8s.tmpl", "project k8s")
+
+	loader := NewLoader()
+	loader.SetTemplateDirs(dirs...)
+
+	want := []string{"powershell", "shell", "explain", "data", "k8s"}
+	if got := loader.GetAvailableTemplates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAvailableTemplates() = %v, want %v", got, want)
+	}
+
+	if got, want := loader.SystemTemplates(), []string{"default", "shell", "powershell", "data", "k8s"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SystemTemplates() = %v, want %v", got, want)
+	}
+
+	text, err := loader.LoadTemplate("k8s")
+	if err != nil || text != "project k8s" {
+		t.Errorf("LoadTemplate(k8s) = %q, %v, want the project template", text, err)
+	}
+	if text, _ := loader.LoadTemplate("shell"); text != ShellAssistant {
+		t.Error("LoadTemplate(shell) did not return the built-in template")
+	}
+}
+
+func TestSetTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "k8s.tmpl", "You run {{.Shell}} on {{.OS}} for {{.User}} in {{.Cwd}}.\n{{.Environment}}\n")
+	writeTemplate(t, dir, "format.tmpl", "{{.OutputFormat}}\nAnswer in {{.Language}}.")
+	writeTemplate(t, dir, "broken.tmpl", "{{.Shell")
+	writeTemplate(t, dir, "missing.tmpl", "{{.Cluster}}")
+
+	tests := []struct {
+		name     string
+		want     []string
+		dontWant []string
+		wantErr  string
+	}{
+		{
+			name: "k8s",
+			want: []string{"You run bash on ", "- Project: Go", "## 输出格式 (Output Format)", `"script": "<shell脚本代码或提示信息>"`},
+		},
+		{
+			name:     "format",
+			want:     []string{"Answer in zh.", "## 输出格式 (Output Format)"},
+			dontWant: []string{"## Initialization"},
+		},
+		{name: "broken", wantErr: "failed to parse template"},
+		{name: "missing", wantErr: "failed to render template"},
+		{name: "explain", wantErr: "cannot be used as the system prompt"},
+		{name: "nope", wantErr: "unknown template: nope"},
+		{name: "default", want: []string{"# Role: "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewLoader()
+			loader.SetShell("bash")
+			loader.SetEnvironment("- Project: Go (go.mod)")
+			loader.SetTemplateDirs(dir)
+
+			err := loader.SetTemplate(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SetTemplate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetTemplate() error = %v", err)
+			}
+			if loader.Template() != tt.name {
+				t.Errorf("Template() = %q, want %q", loader.Template(), tt.name)
+			}
+
+			prompt := loader.LoadSystemPrompt()
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("LoadSystemPrompt() = %q, want it to contain %q", prompt, want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(prompt, dontWant) {
+					t.Errorf("LoadSystemPrompt() = %q, want it not to contain %q", prompt, dontWant)
+				}
+			}
+			if strings.Count(prompt, "## 输出格式") != 1 {
+				t.Errorf("LoadSystemPrompt() has %d output format sections, want 1", strings.Count(prompt, "## 输出格式"))
+			}
+		})
+	}
+}
+
+func TestCreateConversationPromptEscapes(t *testing.T) {
+	systemPrompt := "Use kubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{end}}'"
+	loader := NewLoader()
+
+	template := lcprompts.NewPromptTemplate(loader.CreateConversationPrompt(systemPrompt), []string{"history", "input"})
+	got, err := template.Format(map[string]any{"history": "", "input": "list pods"})
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if !strings.HasPrefix(got, systemPrompt+"\n") || !strings.Contains(got, "Human: list pods") {
+		t.Errorf("Format() = %q, want the system prompt as written", got)
+	}
+}
@@ -0,0 +1,114 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Languages with localized prompts
+const (
+	Chinese = "zh"
+	English = "en"
+)
+
+// DefaultLanguage is the language of the prompts when none is configured
+const DefaultLanguage = Chinese
+
+// promptSet holds the prompts of one language
+type promptSet struct {
+	shell      string
+	powershell string
+	explain    string
+	plan       string
+	agent      string
+	// environment introduces the facts about the environment in the system prompt
+	environment string
+}
+
+// promptSets maps languages to their localized prompts
+var promptSets = map[string]*promptSet{
+	Chinese: {
+		shell:      ShellAssistant,
+		powershell: PowershellAssistant,
+		explain:    ExplainAssistant,
+		plan:       PlanInstruction,
+		agent:      AgentInstruction,
+		environment: "以下是用户当前环境的信息。生成脚本时必须据此选择命令、包管理器和参数，不要再询问这些信息；" +
+			"例如用户要求“运行测试”时，直接使用项目类型对应的测试命令。",
+	},
+	English: {
+		shell:      ShellAssistantEN,
+		powershell: PowershellAssistantEN,
+		explain:    ExplainAssistantEN,
+		plan:       PlanInstructionEN,
+		agent:      AgentInstructionEN,
+		environment: "These facts describe the user's current environment. Use them to choose commands, package managers " +
+			"and options, and do not ask for them again; for example, when the user asks to \"run the tests\", " +
+			"use the test command of the project type directly.",
+	},
+}
+
+// languageAliases map common spellings of the localized languages to their codes
+var languageAliases = map[string]string{
+	"zh": Chinese, "zh-cn": Chinese, "zh_cn": Chinese, "cn": Chinese, "chinese": Chinese, "中文": Chinese,
+	"en": English, "en-us": English, "en_us": English, "en-gb": English, "en_gb": English, "english": English,
+}
+
+// languageNames name common languages without localized prompts in the instruction to answer in them
+var languageNames = map[string]string{
+	"de": "German", "es": "Spanish", "fr": "French", "it": "Italian", "ja": "Japanese",
+	"ko": "Korean", "nl": "Dutch", "pl": "Polish", "pt": "Portuguese", "ru": "Russian",
+	"tr": "Turkish", "uk": "Ukrainian", "vi": "Vietnamese",
+}
+
+// SetLanguage sets the language of the prompts, as a code such as "en" or a name such as "English".
+// Languages without localized prompts use the English ones, with an instruction to answer in that language.
+func (l *Loader) SetLanguage(language string) {
+	language = strings.TrimSpace(language)
+	if language == "" {
+		l.language = DefaultLanguage
+		return
+	}
+	if code, ok := languageAliases[strings.ToLower(language)]; ok {
+		l.language = code
+		return
+	}
+	l.language = language
+}
+
+// Language returns the language of the prompts
+func (l *Loader) Language() string {
+	return l.language
+}
+
+// PlanInstruction returns the instruction prepended to requests in plan mode
+func (l *Loader) PlanInstruction() string {
+	return l.prompts().plan
+}
+
+// AgentInstruction returns the system message sent in agent mode
+func (l *Loader) AgentInstruction() string {
+	return l.prompts().agent + l.languageInstruction()
+}
+
+// prompts returns the prompts of the language, or the English ones if it has none
+func (l *Loader) prompts() *promptSet {
+	if set, ok := promptSets[l.language]; ok {
+		return set
+	}
+	return promptSets[English]
+}
+
+// languageInstruction tells the model to answer in a language without localized prompts.
+// It is empty for localized languages.
+func (l *Loader) languageInstruction() string {
+	if _, ok := promptSets[l.language]; ok {
+		return ""
+	}
+	name := l.language
+	if known, ok := languageNames[strings.ToLower(name)]; ok {
+		name = known
+	}
+	return fmt.Sprintf("\n\n## Language\n\nAlways write explanations, descriptions, questions and warnings in %s. "+
+		"Keep the JSON field names, the risk levels and the scripts as they are.\n", name)
+}
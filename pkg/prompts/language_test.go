@@ -0,0 +1,93 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetLanguage(t *testing.T) {
+	tests := []struct {
+		language     string
+		wantLanguage string
+		want         []string
+		dontWant     []string
+	}{
+		{
+			language:     "",
+			wantLanguage: Chinese,
+			want:         []string{"# Role: Linux系统专家", "## 输出格式 (Output Format)"},
+			dontWant:     []string{"## Language"},
+		},
+		{
+			language:     "en",
+			wantLanguage: English,
+			want:         []string{"# Role: Linux Expert", "## Output Format", "- Project: Go", "These facts describe"},
+			dontWant:     []string{"## Language", "输出格式"},
+		},
+		{
+			language:     "English",
+			wantLanguage: English,
+			want:         []string{"# Role: Linux Expert"},
+		},
+		{
+			language:     "zh-CN",
+			wantLanguage: Chinese,
+			want:         []string{"## 输出格式 (Output Format)"},
+		},
+		{
+			language:     "de",
+			wantLanguage: "de",
+			want:         []string{"# Role: Linux Expert", "## Language", "in German."},
+		},
+		{
+			language:     "Klingon",
+			wantLanguage: "Klingon",
+			want:         []string{"# Role: Linux Expert", "in Klingon."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			loader := NewLoader()
+			loader.SetLanguage(tt.language)
+			loader.SetEnvironment("- Project: Go (go.mod)")
+			if err := loader.SetTemplate("shell"); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := loader.Language(); got != tt.wantLanguage {
+				t.Errorf("Language() = %q, want %q", got, tt.wantLanguage)
+			}
+			prompt := loader.LoadSystemPrompt()
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("LoadSystemPrompt() does not contain %q", want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(prompt, dontWant) {
+					t.Errorf("LoadSystemPrompt() contains %q", dontWant)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalizedInstructions(t *testing.T) {
+	loader := NewLoader()
+	loader.SetLanguage("en")
+	if loader.PlanInstruction() != PlanInstructionEN || loader.AgentInstruction() != AgentInstructionEN {
+		t.Error("English instructions were not selected")
+	}
+	if !strings.Contains(loader.LoadExplainPrompt(), "# Role: Command Line Teacher") {
+		t.Error("LoadExplainPrompt() did not return the English prompt")
+	}
+
+	loader.SetLanguage("fr")
+	if !strings.HasSuffix(loader.AgentInstruction(), "Keep the JSON field names, the risk levels and the scripts as they are.\n") {
+		t.Errorf("AgentInstruction() = %q, want the language instruction", loader.AgentInstruction())
+	}
+	if !strings.Contains(loader.LoadExplainPrompt(), "in French.") {
+		t.Error("LoadExplainPrompt() does not ask for French")
+	}
+}
@@ -3,55 +3,124 @@ package prompts
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
 	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
 )
 
 // Loader handles loading and managing prompts
 type Loader struct {
-	osVersion string
+	osVersion    string
+	environment  string
+	shell        string
+	language     string
+	templateDirs []string
+	// template is the selected template name, empty for the built-in prompt of the OS
+	template string
+	// custom is the selected user-defined template, if any
+	custom *template.Template
 }
 
 // NewLoader creates a new prompt loader
 func NewLoader() *Loader {
 	return &Loader{
 		osVersion: getOSVersion(),
+		language:  DefaultLanguage,
 	}
 }
 
-// LoadSystemPrompt loads the appropriate system prompt based on the OS
+// LoadSystemPrompt loads the selected system prompt in the configured language: the user-defined template
+// if one is selected, otherwise the built-in prompt for the OS. If the template fails to render,
+// the built-in prompt is used.
 func (l *Loader) LoadSystemPrompt() string {
+	if l.custom != nil {
+		prompt, err := l.render(l.custom)
+		if err == nil {
+			return prompt + l.languageInstruction()
+		}
+		logrus.WithError(err).WithField("template", l.template).Warn("Failed to render template, using the built-in prompt")
+	}
+	return l.builtinPrompt() + l.languageInstruction()
+}
+
+// builtinPrompt returns the built-in system prompt with its placeholders replaced.
+// Without a selected template, the prompt matches the configured shell, or the OS if no shell is set.
+func (l *Loader) builtinPrompt() string {
 	var prompt string
-	switch runtime.GOOS {
-	case "windows":
-		prompt = PowershellAssistant
+	switch {
+	case l.template == "powershell":
+		prompt = l.prompts().powershell
+	case l.template == "shell":
+		prompt = l.prompts().shell
+	case l.shell == "pwsh" || l.shell == "powershell":
+		prompt = l.prompts().powershell
+	case l.shell == "" && runtime.GOOS == "windows":
+		prompt = l.prompts().powershell
 	default:
-		prompt = ShellAssistant
+		prompt = l.prompts().shell
+	}
+
+	shell := l.shell
+	if shell == "" {
+		shell = "bash"
 	}
 
 	// Replace template placeholders
 	prompt = strings.ReplaceAll(prompt, "<'>", "`")
 	prompt = strings.ReplaceAll(prompt, "{{.osVersion}}", l.osVersion)
+	prompt = strings.ReplaceAll(prompt, "{{.shell}}", shell)
+	prompt = strings.ReplaceAll(prompt, "{{.environment}}", l.environmentSection())
 
 	return prompt
 }
 
-// LoadTemplate loads a specific prompt template by name
+// SetEnvironment sets the facts about the user's environment included in the system prompt
+func (l *Loader) SetEnvironment(environment string) {
+	l.environment = strings.TrimSpace(environment)
+}
+
+// environmentSection returns the system prompt section describing the environment, or nothing without facts
+func (l *Loader) environmentSection() string {
+	if l.environment == "" {
+		return ""
+	}
+	return "## Environment\n\n" + l.prompts().environment + "\n\n" + l.environment + "\n\n"
+}
+
+// LoadExplainPrompt loads the system prompt used to explain a command, in the configured language
+func (l *Loader) LoadExplainPrompt() string {
+	return strings.ReplaceAll(l.prompts().explain, "{{.osVersion}}", l.osVersion) + l.languageInstruction()
+}
+
+// LoadTemplate loads a specific prompt template by name, built-in ones in the configured language
 func (l *Loader) LoadTemplate(name string) (string, error) {
 	switch name {
 	case "powershell":
-		return PowershellAssistant, nil
+		return l.prompts().powershell, nil
 	case "shell":
-		return ShellAssistant, nil
-	default:
+		return l.prompts().shell, nil
+	case "explain":
+		return l.prompts().explain, nil
+	}
+
+	path, ok := l.userTemplates()[name]
+	if !ok {
 		return "", fmt.Errorf("unknown template: %s", name)
 	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+	return string(text), nil
 }
 
-// GetAvailableTemplates returns a list of available template names
+// GetAvailableTemplates returns the built-in template names followed by the user-defined ones
 func (l *Loader) GetAvailableTemplates() []string {
-	return []string{"powershell", "shell"}
+	return append([]string{"powershell", "shell", "explain"}, l.userTemplateNames()...)
 }
 
 // GetOSVersion returns the operating system version
@@ -111,9 +180,10 @@ func getMacOSVersion() string {
 	return strings.TrimSpace(string(output))
 }
 
-// CreateConversationPrompt creates a conversation prompt template
+// CreateConversationPrompt creates a conversation prompt template.
+// Template delimiters in the system prompt are escaped, so that it renders as written.
 func (l *Loader) CreateConversationPrompt(systemPrompt string) string {
-	return systemPrompt + `
+	return EscapeTemplate(systemPrompt) + `
 Current conversation:
 {{.history}}
 Human: {{.input}}
@@ -0,0 +1,73 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinPromptShell(t *testing.T) {
+	tests := []struct {
+		shell    string
+		template string
+		want     []string
+		dontWant []string
+	}{
+		{
+			shell:    "bash",
+			want:     []string{"# Role: Linux Expert", "into bash scripts", "pasted into a bash terminal"},
+			dontWant: []string{"{{.shell}}"},
+		},
+		{
+			shell:    "zsh",
+			want:     []string{"# Role: Linux Expert", "into zsh scripts", "pasted into a zsh terminal"},
+			dontWant: []string{"bash"},
+		},
+		{
+			shell:    "fish",
+			want:     []string{"# Role: Linux Expert", "into fish scripts", "pasted into a fish terminal"},
+			dontWant: []string{"bash"},
+		},
+		{
+			shell:    "pwsh",
+			want:     []string{"# Role: Windows PowerShell Expert"},
+			dontWant: []string{"bash"},
+		},
+		{
+			shell: "powershell",
+			want:  []string{"# Role: Windows PowerShell Expert"},
+		},
+		{
+			shell:    "pwsh",
+			template: "shell",
+			want:     []string{"# Role: Linux Expert", "into pwsh scripts"},
+		},
+		{
+			shell:    "bash",
+			template: "powershell",
+			want:     []string{"# Role: Windows PowerShell Expert"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell+"/"+tt.template, func(t *testing.T) {
+			loader := NewLoader()
+			loader.SetLanguage(English)
+			loader.SetShell(tt.shell)
+			if err := loader.SetTemplate(tt.template); err != nil {
+				t.Fatal(err)
+			}
+
+			prompt := loader.LoadSystemPrompt()
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("LoadSystemPrompt() does not contain %q", want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(prompt, dontWant) {
+					t.Errorf("LoadSystemPrompt() contains %q", dontWant)
+				}
+			}
+		})
+	}
+}
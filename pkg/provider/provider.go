@@ -0,0 +1,184 @@
+// Package provider builds LLM clients for the configured backend through a registry of provider factories.
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
+
+	"github.com/blysin/autocmdr/pkg/config"
+)
+
+// Built-in provider names
+const (
+	Ollama    = "ollama"
+	OpenAI    = "openai"
+	Anthropic = "anthropic"
+)
+
+// noToken is sent to OpenAI-compatible servers that do not require authentication,
+// because the client refuses to start without a token.
+const noToken = "no-token"
+
+// Factory builds a model from the application configuration
+type Factory func(cfg *config.Config) (llms.Model, error)
+
+// Registry maps provider names to factories
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates a registry with the built-in providers registered
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[string]Factory)}
+	r.Register(Ollama, newOllama)
+	r.Register(OpenAI, newOpenAI)
+	r.Register(Anthropic, newAnthropic)
+	return r
+}
+
+// Register adds or replaces the factory for a provider name
+func (r *Registry) Register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[strings.ToLower(name)] = factory
+}
+
+// New builds the model for the provider named in the configuration
+func (r *Registry) New(cfg *config.Config) (llms.Model, error) {
+	name := strings.ToLower(cfg.Provider)
+	if name == "" {
+		name = Ollama
+	}
+
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s (available: %s)", cfg.Provider, strings.Join(r.Names(), ", "))
+	}
+
+	llm, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s provider: %w", name, err)
+	}
+	return llm, nil
+}
+
+// Names returns the registered provider names in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var defaultRegistry = NewRegistry()
+
+// Register adds a factory to the default registry
+func Register(name string, factory Factory) {
+	defaultRegistry.Register(name, factory)
+}
+
+// New builds a model using the default registry
+func New(cfg *config.Config) (llms.Model, error) {
+	return defaultRegistry.New(cfg)
+}
+
+// Names returns the provider names in the default registry
+func Names() []string {
+	return defaultRegistry.Names()
+}
+
+// SupportsTools reports whether the named provider can call tools, which agent mode needs.
+// The Ollama client does not send tool definitions; Ollama's OpenAI-compatible endpoint can be used
+// with the openai provider instead. Providers registered by other packages are assumed to support tools.
+func SupportsTools(name string) bool {
+	name = strings.ToLower(name)
+	return name != Ollama && name != ""
+}
+
+// newOllama builds an Ollama client, sending the token as a bearer credential if set.
+// Responses follow the requested schema through Ollama's "format" unless structured output is off.
+func newOllama(cfg *config.Config) (llms.Model, error) {
+	client := &http.Client{}
+	if cfg.Token != "" {
+		client = bearerClient(cfg.Token)
+	}
+	transport, err := withStructuredOutput(cfg.StructuredOutput, "/api/chat", ollamaFormat, client.Transport)
+	if err != nil {
+		return nil, err
+	}
+	client.Transport = transport
+
+	return ollama.New(
+		ollama.WithServerURL(cfg.ServerURL),
+		ollama.WithModel(cfg.Model),
+		ollama.WithHTTPClient(client),
+	)
+}
+
+// newOpenAI builds a client for OpenAI-compatible endpoints such as vLLM, llama.cpp server or LM Studio.
+// Responses follow the requested schema through "response_format" unless structured output is off.
+func newOpenAI(cfg *config.Config) (llms.Model, error) {
+	token := cfg.Token
+	if token == "" {
+		token = noToken
+	}
+	transport, err := withStructuredOutput(cfg.StructuredOutput, "/chat/completions", openAIFormat, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return openai.New(
+		openai.WithBaseURL(cfg.ServerURL),
+		openai.WithModel(cfg.Model),
+		openai.WithToken(token),
+		openai.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+}
+
+// newAnthropic builds a client for Anthropic-style messages APIs
+func newAnthropic(cfg *config.Config) (llms.Model, error) {
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("token is required for the anthropic provider")
+	}
+	return anthropic.New(
+		anthropic.WithBaseURL(cfg.ServerURL),
+		anthropic.WithModel(cfg.Model),
+		anthropic.WithToken(cfg.Token),
+	)
+}
+
+// bearerClient returns an HTTP client that adds an Authorization bearer header to each request
+func bearerClient(token string) *http.Client {
+	return &http.Client{
+		Transport: &bearerTransport{token: token, base: http.DefaultTransport},
+	}
+}
+
+// bearerTransport is an http.RoundTripper that sets the Authorization header
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/fake"
+
+	"github.com/blysin/autocmdr/pkg/config"
+)
+
+func TestRegistryNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+	}{
+		{
+			name: "ollama",
+			cfg:  &config.Config{Provider: Ollama, Model: "qwen3:14b", ServerURL: "http://localhost:11434"},
+		},
+		{
+			name: "empty provider defaults to ollama",
+			cfg:  &config.Config{Model: "qwen3:14b", ServerURL: "http://localhost:11434"},
+		},
+		{
+			name: "openai without token",
+			cfg:  &config.Config{Provider: OpenAI, Model: "llama3", ServerURL: "http://localhost:8000/v1"},
+		},
+		{
+			name: "anthropic with token",
+			cfg:  &config.Config{Provider: Anthropic, Model: "claude", ServerURL: "https://api.example.com/v1", Token: "secret"},
+		},
+		{
+			name:    "anthropic without token",
+			cfg:     &config.Config{Provider: Anthropic, Model: "claude", ServerURL: "https://api.example.com/v1"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown structured output mode",
+			cfg:     &config.Config{Provider: Ollama, Model: "qwen3:14b", ServerURL: "http://localhost:11434", StructuredOutput: "grammar"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown provider",
+			cfg:     &config.Config{Provider: "unknown", Model: "m", ServerURL: "http://localhost"},
+			wantErr: true,
+		},
+	}
+
+	registry := NewRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm, err := registry.New(tt.cfg)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if llm == nil {
+				t.Error("expected model but got nil")
+			}
+		})
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	registry := NewRegistry()
+	expected := fake.NewFakeLLM([]string{"ok"})
+	registry.Register("Custom", func(_ *config.Config) (llms.Model, error) {
+		return expected, nil
+	})
+
+	llm, err := registry.New(&config.Config{Provider: "custom"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if llm != expected {
+		t.Error("expected registered model to be returned")
+	}
+
+	names := registry.Names()
+	if !reflect.DeepEqual(names, []string{Anthropic, "custom", Ollama, OpenAI}) {
+		t.Errorf("unexpected provider names: %v", names)
+	}
+}
+
+func TestSupportsTools(t *testing.T) {
+	tests := map[string]bool{
+		Ollama:    false,
+		"Ollama":  false,
+		"":        false,
+		OpenAI:    true,
+		Anthropic: true,
+		"custom":  true,
+	}
+	for name, want := range tests {
+		if got := SupportsTools(name); got != want {
+			t.Errorf("SupportsTools(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestBearerClient(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := bearerClient("secret").Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if got != "Bearer secret" {
+		t.Errorf("expected %q but got %q", "Bearer secret", got)
+	}
+}
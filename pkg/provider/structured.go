@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Structured output modes, from the strongest constraint to none
+const (
+	OutputSchema = "schema"
+	OutputJSON   = "json"
+	OutputOff    = "off"
+)
+
+// OutputModes lists the valid structured output modes
+var OutputModes = []string{OutputSchema, OutputJSON, OutputOff}
+
+// ResponseSchema is the JSON Schema a model response should follow
+type ResponseSchema struct {
+	Name   string
+	Schema map[string]interface{}
+}
+
+// schemaKey is the context key of the response schema
+type schemaKey struct{}
+
+// WithResponseSchema returns a context that asks backends supporting constrained decoding to follow schema.
+// Backends without support ignore it, leaving the prompt to ask for JSON.
+func WithResponseSchema(ctx context.Context, name string, schema map[string]interface{}) context.Context {
+	return context.WithValue(ctx, schemaKey{}, &ResponseSchema{Name: name, Schema: schema})
+}
+
+// formatFunc sets the response format for mode in a chat request body
+type formatFunc func(body map[string]interface{}, mode string, schema *ResponseSchema)
+
+// ollamaFormat sets the "format" of an Ollama chat request
+func ollamaFormat(body map[string]interface{}, mode string, schema *ResponseSchema) {
+	if mode == OutputSchema {
+		body["format"] = schema.Schema
+	} else {
+		body["format"] = "json"
+	}
+}
+
+// openAIFormat sets the "response_format" of an OpenAI-compatible chat request
+func openAIFormat(body map[string]interface{}, mode string, schema *ResponseSchema) {
+	if mode == OutputSchema {
+		body["response_format"] = map[string]interface{}{
+			"type":        "json_schema",
+			"json_schema": map[string]interface{}{"name": schema.Name, "schema": schema.Schema},
+		}
+	} else {
+		body["response_format"] = map[string]interface{}{"type": "json_object"}
+	}
+}
+
+// structuredTransport adds a response format to chat requests whose context carries a ResponseSchema.
+// langchaingo can only send "json" to Ollama and sets the OpenAI response format once per client,
+// so the request body is rewritten instead. When the backend rejects a format with an error about it,
+// weaker ones are tried, and the first one accepted is used for later requests.
+type structuredTransport struct {
+	mu     sync.Mutex
+	modes  []string
+	path   string
+	format formatFunc
+	base   http.RoundTripper
+}
+
+// withStructuredOutput wraps base with a structuredTransport for chat requests to path.
+// It returns base unchanged when mode is OutputOff.
+func withStructuredOutput(mode, path string, format formatFunc, base http.RoundTripper) (http.RoundTripper, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	var modes []string
+	switch strings.ToLower(mode) {
+	case OutputSchema, "":
+		modes = []string{OutputSchema, OutputJSON, OutputOff}
+	case OutputJSON:
+		modes = []string{OutputJSON, OutputOff}
+	case OutputOff:
+		return base, nil
+	default:
+		return nil, fmt.Errorf("unknown structured output mode: %s (available: %s)", mode, strings.Join(OutputModes, ", "))
+	}
+	return &structuredTransport{modes: modes, path: path, format: format, base: base}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *structuredTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	schema, ok := req.Context().Value(schemaKey{}).(*ResponseSchema)
+	if !ok || req.Method != http.MethodPost || req.Body == nil || !strings.HasSuffix(req.URL.Path, t.path) {
+		return t.base.RoundTrip(req)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return t.base.RoundTrip(withBody(req, data))
+	}
+
+	modes := t.currentModes()
+	for i, mode := range modes {
+		payload := data
+		if mode != OutputOff {
+			t.format(body, mode, schema)
+			if payload, err = json.Marshal(body); err != nil {
+				return nil, fmt.Errorf("failed to marshal request body: %w", err)
+			}
+		}
+
+		resp, err := t.base.RoundTrip(withBody(req, payload))
+		if err != nil {
+			return nil, err
+		}
+		if i == len(modes)-1 || !formatRejected(resp) {
+			if resp.StatusCode < http.StatusBadRequest {
+				t.keepModes(modes[i:])
+			}
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+	}
+	return nil, fmt.Errorf("no structured output mode to try")
+}
+
+// currentModes returns the modes to try, strongest first
+func (t *structuredTransport) currentModes() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.modes
+}
+
+// keepModes remembers the modes to try for later requests
+func (t *structuredTransport) keepModes(modes []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.modes = modes
+}
+
+// formatErrors are words in error responses that point at the response format rather than at another
+// problem with the request, such as a wrong model name or an oversized context
+var formatErrors = []string{"format", "response_format", "json_schema"}
+
+// formatRejected reports whether the backend rejected the response format of a request.
+// The body of resp is read and replaced, so that the response can still be returned.
+func formatRejected(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusNotImplemented:
+	default:
+		return false
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	message := strings.ToLower(string(data))
+	for _, word := range formatErrors {
+		if strings.Contains(message, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// withBody returns a copy of req that sends data as its body
+func withBody(req *http.Request, data []byte) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(data))
+	clone.ContentLength = int64(len(data))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return clone
+}
@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+var testSchema = map[string]interface{}{
+	"type":       "object",
+	"properties": map[string]interface{}{"script": map[string]interface{}{"type": "string"}},
+}
+
+// formatServer records the value of key in each request body and rejects the formats in reject
+func formatServer(t *testing.T, key string, reject func(format interface{}) bool) (*httptest.Server, *[]interface{}) {
+	t.Helper()
+	var formats []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		formats = append(formats, body[key])
+		if reject(body[key]) {
+			http.Error(w, `{"error": "invalid `+key+`"}`, http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &formats
+}
+
+// post sends a chat request through transport, optionally carrying the test schema
+func post(t *testing.T, transport http.RoundTripper, url string, withSchema bool) int {
+	t.Helper()
+	ctx := context.Background()
+	if withSchema {
+		ctx = WithResponseSchema(ctx, "result", testSchema)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader([]byte(`{"model": "m", "format": ""}`)))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestStructuredTransportOllama(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		reject   func(format interface{}) bool
+		expected []interface{}
+	}{
+		{
+			name:     "schema accepted",
+			mode:     OutputSchema,
+			reject:   func(interface{}) bool { return false },
+			expected: []interface{}{testSchema, testSchema},
+		},
+		{
+			name: "schema rejected falls back to json",
+			mode: OutputSchema,
+			reject: func(format interface{}) bool {
+				_, isObject := format.(map[string]interface{})
+				return isObject
+			},
+			expected: []interface{}{testSchema, "json", "json"},
+		},
+		{
+			name:     "json rejected falls back to the prompt",
+			mode:     OutputJSON,
+			reject:   func(format interface{}) bool { return format == "json" },
+			expected: []interface{}{"json", "", ""},
+		},
+		{
+			name:     "off",
+			mode:     OutputOff,
+			reject:   func(interface{}) bool { return false },
+			expected: []interface{}{"", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, formats := formatServer(t, "format", tt.reject)
+			transport, err := withStructuredOutput(tt.mode, "/api/chat", ollamaFormat, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if status := post(t, transport, server.URL+"/api/chat", true); status != http.StatusOK {
+					t.Fatalf("request %d: expected status 200 but got %d", i, status)
+				}
+			}
+			if !reflect.DeepEqual(*formats, tt.expected) {
+				t.Errorf("expected formats %v but got %v", tt.expected, *formats)
+			}
+		})
+	}
+}
+
+func TestStructuredTransportOpenAI(t *testing.T) {
+	server, formats := formatServer(t, "response_format", func(interface{}) bool { return false })
+	transport, err := withStructuredOutput(OutputSchema, "/chat/completions", openAIFormat, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	post(t, transport, server.URL+"/v1/chat/completions", true)
+
+	expected := map[string]interface{}{
+		"type":        "json_schema",
+		"json_schema": map[string]interface{}{"name": "result", "schema": testSchema},
+	}
+	if len(*formats) != 1 || !reflect.DeepEqual((*formats)[0], expected) {
+		t.Errorf("expected response format %v but got %v", expected, *formats)
+	}
+}
+
+func TestStructuredTransportPassesThrough(t *testing.T) {
+	server, formats := formatServer(t, "format", func(interface{}) bool { return true })
+	transport, err := withStructuredOutput(OutputSchema, "/api/chat", ollamaFormat, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Requests without a schema, and rejections unrelated to the format, are left alone
+	if status := post(t, transport, server.URL+"/api/chat", false); status != http.StatusBadRequest {
+		t.Errorf("expected status 400 but got %d", status)
+	}
+	if status := post(t, transport, server.URL+"/api/embed", true); status != http.StatusBadRequest {
+		t.Errorf("expected status 400 but got %d", status)
+	}
+	if !reflect.DeepEqual(*formats, []interface{}{"", ""}) {
+		t.Errorf("expected the request bodies unchanged, got formats %v", *formats)
+	}
+
+	// When every format is rejected, the modes tried first are kept for the next request
+	post(t, transport, server.URL+"/api/chat", true)
+	post(t, transport, server.URL+"/api/chat", true)
+	if len(*formats) != 8 || !reflect.DeepEqual((*formats)[5], testSchema) {
+		t.Errorf("expected the schema to be tried again, got formats %v", *formats)
+	}
+}
+
+func TestStructuredTransportKeepsModeOnOtherErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "unknown model", status: http.StatusBadRequest, body: `{"error": "model \"m\" not found, try pulling it first"}`},
+		{name: "context too long", status: http.StatusBadRequest, body: `{"error": "input length exceeds the context length"}`},
+		{name: "unauthorized", status: http.StatusUnauthorized, body: `{"error": "invalid api key"}`},
+		{name: "server error", status: http.StatusInternalServerError, body: `{"error": "invalid format"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				http.Error(w, tt.body, tt.status)
+			}))
+			t.Cleanup(server.Close)
+			transport, err := withStructuredOutput(OutputSchema, "/api/chat", ollamaFormat, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if status := post(t, transport, server.URL+"/api/chat", true); status != tt.status {
+				t.Errorf("expected status %d but got %d", tt.status, status)
+			}
+			if requests != 1 {
+				t.Errorf("expected the request to be sent once but it was sent %d times", requests)
+			}
+			if modes := transport.(*structuredTransport).currentModes(); modes[0] != OutputSchema {
+				t.Errorf("expected the schema mode to be kept but got %v", modes)
+			}
+		})
+	}
+}
+
+func TestWithStructuredOutputUnknownMode(t *testing.T) {
+	if _, err := withStructuredOutput("grammar", "/api/chat", ollamaFormat, nil); err == nil {
+		t.Error("expected error but got none")
+	}
+}
@@ -0,0 +1,777 @@
+// Package risk classifies generated shell scripts by how much damage they can do,
+// so that the confirmation asked of the user can scale with the risk.
+package risk
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Level is the risk tier of a script
+type Level int
+
+// Risk tiers, ordered from least to most dangerous
+const (
+	ReadOnly Level = iota
+	Mutating
+	Destructive
+	Privileged
+)
+
+// String returns the display name of the level
+func (l Level) String() string {
+	switch l {
+	case ReadOnly:
+		return "read-only"
+	case Mutating:
+		return "mutating"
+	case Destructive:
+		return "destructive"
+	case Privileged:
+		return "privileged"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// Finding describes one reason that raised the risk level of a script
+type Finding struct {
+	Level   Level  `json:"level"`
+	Command string `json:"command"`
+	Reason  string `json:"reason"`
+}
+
+// Command is a simple command found in a script, with wrappers such as sudo resolved
+type Command struct {
+	Name string   `json:"name"`
+	Args []string `json:"args,omitempty"`
+}
+
+// Assessment is the result of analyzing a script
+type Assessment struct {
+	Level     Level     `json:"level"`
+	Findings  []Finding `json:"findings,omitempty"`
+	FirstWord string    `json:"first_word"`
+	Commands  []Command `json:"commands,omitempty"`
+	Redirects []string  `json:"redirects,omitempty"`
+	// Guesses holds the possible commands of a script that could not be parsed
+	Guesses []Command `json:"guesses,omitempty"`
+}
+
+// wrappers run the command given in their arguments
+var wrappers = map[string]bool{
+	"env": true, "nohup": true, "time": true, "nice": true, "ionice": true,
+	"timeout": true, "xargs": true, "exec": true, "command": true, "builtin": true,
+	"stdbuf": true, "watch": true,
+	// fish keywords that bash parses as commands
+	"and": true, "or": true, "not": true,
+}
+
+// elevators run the command given in their arguments with elevated privileges
+var elevators = map[string]bool{
+	"sudo": true, "doas": true, "su": true, "pkexec": true, "runas": true, "gsudo": true,
+}
+
+// interpreters execute code read from stdin when used at the end of a pipe
+var interpreters = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true, "ksh": true,
+	"python": true, "python3": true, "perl": true, "ruby": true, "node": true,
+	"pwsh": true, "powershell": true, "iex": true, "invoke-expression": true,
+}
+
+// destructiveCommands are destructive regardless of their arguments
+var destructiveCommands = map[string]string{
+	"dd":               "writes raw data to files or devices",
+	"shred":            "irrecoverably overwrites files",
+	"wipefs":           "erases filesystem signatures",
+	"fdisk":            "modifies partition tables",
+	"sfdisk":           "modifies partition tables",
+	"parted":           "modifies partition tables",
+	"mkswap":           "formats a swap area",
+	"shutdown":         "shuts down the system",
+	"reboot":           "reboots the system",
+	"poweroff":         "powers off the system",
+	"halt":             "halts the system",
+	"format":           "formats a volume",
+	"format-volume":    "formats a volume",
+	"clear-disk":       "erases a disk",
+	"remove-partition": "removes a partition",
+}
+
+// readOnlyCommands do not modify the system unless flagged otherwise by inspectArgs
+var readOnlyCommands = map[string]bool{
+	"ls": true, "ll": true, "dir": true, "cat": true, "less": true, "more": true, "head": true, "tail": true,
+	"grep": true, "egrep": true, "fgrep": true, "rg": true, "ag": true, "find": true, "fd": true, "locate": true,
+	"wc": true, "du": true, "df": true, "ps": true, "pgrep": true, "top": true, "htop": true, "free": true,
+	"uptime": true, "echo": true, "printf": true, "pwd": true, "which": true, "whereis": true, "type": true,
+	"whoami": true, "id": true, "groups": true, "hostname": true, "uname": true, "date": true, "cal": true,
+	"stat": true, "file": true, "tree": true, "sort": true, "uniq": true, "cut": true, "tr": true, "awk": true,
+	"sed": true, "jq": true, "yq": true, "column": true, "diff": true, "cmp": true, "comm": true, "md5sum": true,
+	"sha1sum": true, "sha256sum": true, "basename": true, "dirname": true, "realpath": true, "readlink": true,
+	"printenv": true, "lsof": true, "netstat": true, "ss": true, "ip": true, "ifconfig": true, "ping": true,
+	"dig": true, "nslookup": true, "host": true, "traceroute": true, "curl": true, "wget": true,
+	"lsblk": true, "blkid": true, "mount": true, "journalctl": true, "dmesg": true, "history": true,
+	"test": true, "[": true, "true": true, "false": true, "tee": true, "seq": true, "xxd": true, "od": true,
+	"hexdump": true, "strings": true, "nproc": true, "lscpu": true, "env": true, "man": true, "git": true,
+	"docker": true, "kubectl": true, "systemctl": true, "go": true, "npm": true, "tar": true, "zcat": true,
+}
+
+// readOnlySubcommands lists the subcommands that keep multi-purpose tools read-only
+var readOnlySubcommands = map[string]map[string]bool{
+	"git": {
+		"status": true, "log": true, "diff": true, "show": true, "branch": true, "remote": true,
+		"blame": true, "rev-parse": true, "describe": true, "tag": true, "ls-files": true, "grep": true,
+	},
+	"docker": {
+		"ps": true, "images": true, "logs": true, "inspect": true, "info": true, "version": true, "stats": true,
+	},
+	"kubectl": {
+		"get": true, "describe": true, "logs": true, "top": true, "explain": true, "version": true,
+	},
+	"systemctl": {
+		"status": true, "is-active": true, "is-enabled": true, "list-units": true, "list-unit-files": true, "show": true,
+	},
+	"go":  {"version": true, "env": true, "list": true, "doc": true, "vet": true},
+	"npm": {"ls": true, "list": true, "view": true, "outdated": true},
+	"ip":  {"addr": true, "a": true, "route": true, "r": true, "link": true, "neigh": true},
+}
+
+// readOnlyActions are the action words that keep the objects of ip read-only, as in ip addr show.
+// Any other action, such as set, flush or del, changes network settings.
+var readOnlyActions = map[string]bool{
+	"show": true, "s": true, "sh": true, "list": true, "ls": true, "lst": true, "l": true, "get": true, "help": true,
+}
+
+// gitRefChanges are the flags that make git branch and git tag create, change or delete refs
+var gitRefChanges = map[string][]string{
+	"branch": {"d", "m", "c", "u", "-delete", "-move", "-copy", "-set-upstream-to", "-unset-upstream", "-edit-description"},
+	"tag":    {"d", "a", "s", "u", "f", "m", "-delete", "-annotate", "-sign", "-local-user", "-force", "-message", "-file"},
+}
+
+// gitRefListing are the flags that make git branch and git tag list refs matching their arguments instead of creating them
+var gitRefListing = map[string][]string{
+	"branch": {
+		"l", "a", "r", "v", "-list", "-all", "-remotes", "-verbose", "-contains", "-no-contains",
+		"-merged", "-no-merged", "-points-at", "-show-current", "-sort", "-format", "-column",
+	},
+	"tag": {
+		"l", "n", "v", "-list", "-verify", "-contains", "-no-contains", "-merged", "-no-merged",
+		"-points-at", "-sort", "-format", "-column",
+	},
+}
+
+// readOnlyVerbs are PowerShell cmdlet verbs that do not modify the system
+var readOnlyVerbs = map[string]bool{
+	"get": true, "test": true, "select": true, "measure": true, "where": true, "sort": true,
+	"format": true, "out": true, "write": true, "compare": true, "find": true, "resolve": true,
+}
+
+// safeRedirectTargets can be written to without modifying anything
+var safeRedirectTargets = map[string]bool{
+	"/dev/null": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true, "nul": true, "$null": true,
+}
+
+// Analyze parses a script and classifies its risk.
+// Scripts that cannot be parsed are classified as mutating.
+func Analyze(script string) *Assessment {
+	assessment := &Assessment{Level: ReadOnly}
+
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(script), "")
+	if err != nil {
+		assessment.FirstWord = firstField(script)
+		assessment.Guesses = guessCommands(script)
+		assessment.add(Mutating, "", fmt.Sprintf("script could not be parsed: %v", err))
+		return assessment
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CallExpr:
+			args := literalArgs(n.Args)
+			if assessment.FirstWord == "" && len(args) > 0 {
+				assessment.FirstWord = args[0]
+			}
+			assessment.inspectCall(args)
+		case *syntax.Redirect:
+			assessment.inspectRedirect(n)
+		case *syntax.BinaryCmd:
+			assessment.inspectPipe(n)
+		case *syntax.FuncDecl:
+			assessment.add(Mutating, n.Name.Value, "defines a shell function")
+		}
+		return true
+	})
+
+	if assessment.FirstWord == "" {
+		assessment.FirstWord = firstField(script)
+	}
+	return assessment
+}
+
+// add records a finding and raises the assessment level if needed
+func (a *Assessment) add(level Level, command, reason string) {
+	a.Findings = append(a.Findings, Finding{Level: level, Command: command, Reason: reason})
+	if level > a.Level {
+		a.Level = level
+	}
+}
+
+// inspectCall classifies a simple command given its literal arguments
+func (a *Assessment) inspectCall(args []string) {
+	if len(args) == 0 {
+		return
+	}
+
+	raw := args[0]
+	if raw == "" {
+		a.Commands = append(a.Commands, Command{Args: args[1:]})
+		a.add(Mutating, "", "command name is computed at runtime")
+		return
+	}
+	name := commandName(raw)
+	rest := args[1:]
+	a.Commands = append(a.Commands, Command{Name: name, Args: rest})
+
+	if elevators[name] {
+		a.add(Privileged, name, "runs a command with elevated privileges")
+		if name == "su" {
+			a.inspectInline(optionValue(rest, "-c", "--command"))
+			return
+		}
+		a.inspectCall(positional(name, rest))
+		return
+	}
+	if wrappers[name] {
+		if wrapped := positional(name, rest); len(wrapped) > 0 {
+			a.inspectCall(wrapped)
+			return
+		}
+	}
+	if name == "eval" {
+		a.inspectInline(strings.Join(rest, " "))
+		return
+	}
+	if interpreters[name] {
+		if inline := optionValue(rest, "-c", "-command"); inline != "" {
+			a.inspectInline(inline)
+			return
+		}
+	}
+
+	if reason, ok := destructiveCommands[name]; ok {
+		a.add(Destructive, name, reason)
+		return
+	}
+	if strings.HasPrefix(name, "mkfs") {
+		a.add(Destructive, name, "formats a filesystem")
+		return
+	}
+
+	if level, reason := inspectArgs(name, rest); level > ReadOnly {
+		a.add(level, name, reason)
+		return
+	}
+
+	if readOnlyCommands[name] || isReadOnlyCmdlet(name) {
+		return
+	}
+	a.add(Mutating, name, "command may modify the system")
+}
+
+// inspectInline analyzes a script passed as a string argument, as in bash -c '...'
+func (a *Assessment) inspectInline(script string) {
+	if strings.TrimSpace(script) == "" {
+		return
+	}
+	inner := Analyze(script)
+	for _, finding := range inner.Findings {
+		a.add(finding.Level, finding.Command, finding.Reason)
+	}
+	a.Commands = append(a.Commands, inner.Commands...)
+	a.Redirects = append(a.Redirects, inner.Redirects...)
+}
+
+// inspectArgs classifies commands whose risk depends on their arguments
+func inspectArgs(name string, args []string) (Level, string) {
+	switch name {
+	case "rm", "rmdir", "remove-item", "del", "erase", "rd":
+		if hasFlag(args, "r", "R", "-recursive", "recurse") {
+			if touchesCriticalPath(args) {
+				return Destructive, "recursively deletes a critical path"
+			}
+			return Destructive, "recursively deletes files"
+		}
+		if hasFlag(args, "f", "-force", "force") && hasGlob(args) {
+			return Destructive, "force deletes files matching a glob"
+		}
+		return Mutating, "deletes files"
+	case "chmod", "chown", "chgrp", "setfacl":
+		if hasFlag(args, "R", "-recursive") {
+			return Destructive, "recursively changes permissions or ownership"
+		}
+		return Mutating, "changes permissions or ownership"
+	case "find":
+		for i, arg := range args {
+			if arg == "-delete" {
+				return Destructive, "deletes the files it finds"
+			}
+			if arg == "-fprint" || arg == "-fprint0" || arg == "-fprintf" || arg == "-fls" {
+				return Mutating, "writes the files it finds to a file"
+			}
+			if (arg == "-exec" || arg == "-execdir" || arg == "-ok") && i+1 < len(args) {
+				next := commandName(args[i+1])
+				if next == "rm" || next == "shred" {
+					return Destructive, "deletes the files it finds"
+				}
+				if !readOnlyCommands[next] {
+					return Mutating, "runs a command on each file it finds"
+				}
+			}
+		}
+	case "awk", "gawk", "mawk", "nawk":
+		for _, arg := range args {
+			if awkProgramEffect.MatchString(arg) {
+				return Mutating, "runs commands or writes to files"
+			}
+		}
+	case "sed", "perl":
+		if hasFlag(args, "i", "-in-place") {
+			return Mutating, "edits files in place"
+		}
+	case "curl":
+		if hasFlag(args, "o", "O", "-output", "-remote-name") {
+			return Mutating, "writes downloaded content to a file"
+		}
+		if hasFlag(args, "X", "-request", "d", "-data", "F", "-form", "T", "-upload-file") {
+			return Mutating, "sends data to a remote server"
+		}
+	case "wget":
+		if !hasFlag(args, "-spider") {
+			return Mutating, "writes downloaded content to a file"
+		}
+	case "tar":
+		if hasFlag(args, "-extract", "-create") || hasLetterFlag(args, "xc") || hasBundledFlag(args, 'x') || hasBundledFlag(args, 'c') {
+			return Mutating, "creates or extracts an archive"
+		}
+	case "tee":
+		if len(skipOptions(args)) > 0 {
+			return Mutating, "writes to files"
+		}
+	case "mount":
+		if len(skipOptions(args)) > 0 {
+			return Mutating, "mounts a filesystem"
+		}
+	case "truncate":
+		return Destructive, "truncates files"
+	case "date":
+		if hasFlag(args, "s", "-set") {
+			return Mutating, "sets the system clock"
+		}
+	case "hostname":
+		if len(skipOptions(args)) > 0 {
+			return Mutating, "sets the host name"
+		}
+	case "kill", "pkill", "killall", "stop-process":
+		return Mutating, "terminates processes"
+	case "start-process":
+		for _, arg := range args {
+			if strings.EqualFold(arg, "runas") {
+				return Privileged, "starts a process as administrator"
+			}
+		}
+	}
+
+	if allowed, ok := readOnlySubcommands[name]; ok {
+		sub := positional(name, args)
+		if name == "git" && setsGitConfig(args[:len(args)-len(sub)]) {
+			return Mutating, "git -c sets configuration that can run commands"
+		}
+		if len(sub) == 0 {
+			return ReadOnly, ""
+		}
+		if name == "git" && (sub[0] == "clean" || (sub[0] == "reset" && hasFlag(sub[1:], "-hard")) ||
+			(sub[0] == "push" && hasFlag(sub[1:], "f", "-force"))) {
+			return Destructive, fmt.Sprintf("git %s discards work", sub[0])
+		}
+		if !allowed[sub[0]] {
+			return Mutating, fmt.Sprintf("%s %s may modify state", name, sub[0])
+		}
+		if reason := changingAction(name, sub); reason != "" {
+			return Mutating, reason
+		}
+	}
+	return ReadOnly, ""
+}
+
+// changingAction returns why a read-only subcommand changes state given the arguments that follow it,
+// as in ip link set, git remote remove or git branch -D, or "" if it does not
+func changingAction(name string, sub []string) string {
+	action := skipOptions(sub[1:])
+	switch {
+	case name == "ip":
+		if len(action) > 0 && !readOnlyActions[action[0]] {
+			return fmt.Sprintf("ip %s %s changes network settings", sub[0], action[0])
+		}
+	case name == "git" && sub[0] == "remote":
+		if len(action) > 0 && action[0] != "show" && action[0] != "get-url" {
+			return fmt.Sprintf("git remote %s changes the remotes", action[0])
+		}
+	case name == "git" && (sub[0] == "branch" || sub[0] == "tag"):
+		if hasFlag(sub[1:], gitRefChanges[sub[0]]...) || (len(action) > 0 && !hasFlag(sub[1:], gitRefListing[sub[0]]...)) {
+			return fmt.Sprintf("git %s creates, changes or deletes refs", sub[0])
+		}
+	}
+	return ""
+}
+
+// inspectRedirect flags output redirections that write to files or devices
+func (a *Assessment) inspectRedirect(r *syntax.Redirect) {
+	switch r.Op {
+	case syntax.RdrOut, syntax.ClbOut, syntax.RdrAll, syntax.AppOut, syntax.AppAll, syntax.RdrInOut:
+	default:
+		return
+	}
+	if r.Word == nil {
+		return
+	}
+
+	target := wordString(r.Word)
+	if safeRedirectTargets[strings.ToLower(target)] {
+		return
+	}
+	a.Redirects = append(a.Redirects, target)
+	if strings.HasPrefix(target, "/dev/") {
+		a.add(Destructive, target, "writes directly to a device")
+		return
+	}
+	if r.Op == syntax.AppOut || r.Op == syntax.AppAll {
+		a.add(Mutating, target, "appends to a file")
+		return
+	}
+	if touchesCriticalPath([]string{target}) {
+		a.add(Destructive, target, "overwrites a system file")
+		return
+	}
+	a.add(Mutating, target, "overwrites a file")
+}
+
+// inspectPipe flags pipelines that feed data into an interpreter, such as curl | sh
+func (a *Assessment) inspectPipe(b *syntax.BinaryCmd) {
+	if b.Op != syntax.Pipe && b.Op != syntax.PipeAll {
+		return
+	}
+	call, ok := b.Y.Cmd.(*syntax.CallExpr)
+	if !ok {
+		return
+	}
+
+	args := literalArgs(call.Args)
+	for len(args) > 0 && (elevators[commandName(args[0])] || wrappers[commandName(args[0])]) {
+		args = positional(commandName(args[0]), args[1:])
+	}
+	if len(args) == 0 || !interpreters[commandName(args[0])] {
+		return
+	}
+
+	reason := "pipes data into an interpreter"
+	if pipesDownload(b.X) {
+		reason = "pipes downloaded content into an interpreter"
+	}
+	a.add(Destructive, commandName(args[0]), reason)
+}
+
+// pipesDownload reports whether a statement contains a curl or wget call
+func pipesDownload(stmt *syntax.Stmt) bool {
+	found := false
+	syntax.Walk(stmt, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok && len(call.Args) > 0 {
+			name := commandName(wordString(call.Args[0]))
+			if name == "curl" || name == "wget" || name == "invoke-webrequest" || name == "iwr" {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// criticalPaths are locations whose recursive removal or overwrite is catastrophic
+var criticalPaths = []string{
+	"/", "/*", "~", "~/", "~/*", "$HOME", "${HOME}", ".", "./", "..", "*",
+	"/etc", "/usr", "/bin", "/sbin", "/lib", "/boot", "/var", "/home", "/root", "/opt", "/dev", "/sys", "/proc",
+	`c:\`, `c:\windows`, `c:\program files`, `c:\users`,
+}
+
+// criticalPrefixes are directories whose contents are critical to the system
+var criticalPrefixes = []string{"/etc/", "/boot/", "/usr/bin/", "/usr/lib/", "/bin/", "/sbin/", "/lib/"}
+
+// touchesCriticalPath reports whether any argument targets a critical path
+func touchesCriticalPath(args []string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			continue
+		}
+		clean := strings.ToLower(strings.TrimRight(arg, `/\`))
+		if clean == "" {
+			clean = "/"
+		}
+		for _, p := range criticalPaths {
+			if clean == p {
+				return true
+			}
+		}
+		for _, prefix := range criticalPrefixes {
+			if strings.HasPrefix(clean, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxBundledFlags is the longest single-dash argument read as bundled single-letter flags, as in -xzvf.
+// Longer ones are words, such as the PowerShell parameter -Force.
+const maxBundledFlags = 4
+
+// hasFlag reports whether args contain any of the given flags.
+// Single-letter flags also match when bundled, as in -rf.
+func hasFlag(args []string, flags ...string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+		name := strings.TrimPrefix(arg, "-")
+		if eq := strings.Index(name, "="); eq != -1 {
+			name = name[:eq]
+		}
+		for _, flag := range flags {
+			if strings.EqualFold(name, flag) {
+				return true
+			}
+			if len(flag) == 1 && isFlagBundle(name) && strings.Contains(name, flag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isFlagBundle reports whether the name of a single-dash argument is a bundle of single-letter flags
+func isFlagBundle(name string) bool {
+	if name == "" || len(name) > maxBundledFlags {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// hasLetterFlag reports whether any single-dash argument holds one of the letters, however long the bundle,
+// for commands such as tar whose options are all single letters, as in -xzvpf
+func hasLetterFlag(args []string, letters string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsAny(arg[1:], letters) {
+			return true
+		}
+	}
+	return false
+}
+
+// setsGitConfig reports whether the options before a git subcommand set configuration, which can name
+// programs that git runs, such as core.pager or core.fsmonitor
+func setsGitConfig(options []string) bool {
+	for _, option := range options {
+		if option == "-c" || strings.HasPrefix(option, "--config-env") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBundledFlag reports whether the first argument is a dash-less option bundle, as in tar xzf
+func hasBundledFlag(args []string, flag byte) bool {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return false
+	}
+	return strings.IndexByte(args[0], flag) != -1 && strings.Trim(args[0], "cxtvzjJfpC") == ""
+}
+
+// awkProgramEffect matches awk programs that run commands or write to files,
+// through system(), print or printf redirected to a file, or a pipe to or from a command
+var awkProgramEffect = regexp.MustCompile(`system\s*\(|printf?\b[^;{}]*>|\|`)
+
+// hasGlob reports whether any non-flag argument contains a glob pattern
+func hasGlob(args []string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") && strings.ContainsAny(arg, "*?[") {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadOnlyCmdlet reports whether a PowerShell cmdlet uses a read-only verb, as in Get-ChildItem
+func isReadOnlyCmdlet(name string) bool {
+	verb, _, ok := strings.Cut(name, "-")
+	return ok && readOnlyVerbs[verb]
+}
+
+// commandName normalizes a command word to a lower-case base name without extension
+func commandName(word string) string {
+	name := strings.ToLower(filepath.Base(strings.ReplaceAll(word, `\`, "/")))
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// optionValues lists the options of wrapper commands that take a separate value
+var optionValues = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-C": true, "-D": true, "-h": true, "-p": true, "-U": true, "-r": true, "-t": true, "-T": true},
+	"doas":    {"-u": true, "-C": true},
+	"env":     {"-u": true, "-C": true, "-S": true},
+	"nice":    {"-n": true},
+	"ionice":  {"-c": true, "-n": true, "-p": true},
+	"timeout": {"-s": true, "-k": true},
+	"xargs":   {"-I": true, "-n": true, "-P": true, "-d": true, "-L": true, "-s": true, "-a": true, "-E": true},
+	"watch":   {"-n": true},
+	"git":     {"-C": true, "-c": true},
+	"kubectl": {"-n": true, "--namespace": true, "--context": true},
+	"docker":  {"-H": true, "--context": true},
+}
+
+// positional returns the arguments starting at the first positional one,
+// skipping options, option values, environment assignments and durations.
+func positional(name string, args []string) []string {
+	values := optionValues[name]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return args[i+1:]
+		case values[arg]:
+			i++
+		case strings.HasPrefix(arg, "-"):
+		case strings.Contains(arg, "=") && !strings.HasPrefix(arg, "="):
+		case isDuration(arg):
+		default:
+			return args[i:]
+		}
+	}
+	return nil
+}
+
+// optionValue returns the value following the first of the given options
+func optionValue(args []string, options ...string) string {
+	for i, arg := range args {
+		for _, option := range options {
+			if strings.EqualFold(arg, option) && i+1 < len(args) {
+				return args[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// isDuration reports whether s is a number with an optional time unit, as used by timeout and nice
+func isDuration(s string) bool {
+	s = strings.TrimRight(s, "smhd")
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// skipOptions drops leading options from an argument list
+func skipOptions(args []string) []string {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[i+1:]
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return args[i:]
+		}
+	}
+	return nil
+}
+
+// literalArgs returns the literal value of each word; dynamic parts are kept as written
+func literalArgs(words []*syntax.Word) []string {
+	args := make([]string, 0, len(words))
+	for _, word := range words {
+		args = append(args, wordString(word))
+	}
+	return args
+}
+
+// wordString flattens a word into a string, removing quotes.
+// Parameter expansions are rendered as $name and other dynamic parts are dropped.
+func wordString(word *syntax.Word) string {
+	var sb strings.Builder
+	writeParts(&sb, word.Parts)
+	return sb.String()
+}
+
+func writeParts(sb *strings.Builder, parts []syntax.WordPart) {
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			sb.WriteString(p.Value)
+		case *syntax.SglQuoted:
+			sb.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			writeParts(sb, p.Parts)
+		case *syntax.ParamExp:
+			if p.Param != nil {
+				sb.WriteString("$" + p.Param.Value)
+			}
+		}
+	}
+}
+
+// firstField returns the first whitespace-separated word of a script
+// guessCommands splits a script that is not valid bash, such as a fish or PowerShell script, at separators
+// and takes every word as a possible command whose arguments are the words that follow it.
+// Keywords such as if or not therefore cannot hide the commands after them.
+func guessCommands(script string) []Command {
+	var commands []Command
+	segments := strings.FieldsFunc(script, func(r rune) bool {
+		return strings.ContainsRune("\n;|&(){}`", r)
+	})
+	for _, segment := range segments {
+		words := strings.Fields(segment)
+		for i := range words {
+			words[i] = strings.Trim(words[i], `"'`)
+		}
+		for i, word := range words {
+			if word == "" || strings.HasPrefix(word, "-") {
+				continue
+			}
+			commands = append(commands, Command{Name: commandName(word), Args: words[i+1:]})
+		}
+	}
+	return commands
+}
+
+func firstField(script string) string {
+	fields := strings.Fields(script)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
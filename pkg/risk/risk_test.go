@@ -0,0 +1,127 @@
+package risk
+
+import "testing"
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		expected  Level
+		firstWord string
+	}{
+		{name: "list files", script: "ls -la", expected: ReadOnly, firstWord: "ls"},
+		{name: "read-only pipeline", script: "ps aux | grep nginx | awk '{print $2}'", expected: ReadOnly, firstWord: "ps"},
+		{name: "find without actions", script: "find . -name '*.log' -size +1G", expected: ReadOnly, firstWord: "find"},
+		{name: "redirect to dev null", script: "ls /tmp 2>/dev/null", expected: ReadOnly, firstWord: "ls"},
+		{name: "git status", script: "git -C repo status --short", expected: ReadOnly, firstWord: "git"},
+		{name: "fish and keyword", script: "test -d build; and rm -rf build", expected: Destructive, firstWord: "test"},
+		{name: "powershell read-only cmdlet", script: "Get-ChildItem -Force", expected: ReadOnly, firstWord: "Get-ChildItem"},
+		{name: "make directory", script: "mkdir -p build", expected: Mutating, firstWord: "mkdir"},
+		{name: "remove single file", script: "rm notes.txt", expected: Mutating, firstWord: "rm"},
+		{name: "overwrite redirect", script: "echo hello > out.txt", expected: Mutating, firstWord: "echo"},
+		{name: "append redirect", script: "echo hello >> out.txt", expected: Mutating, firstWord: "echo"},
+		{name: "sed in place", script: "sed -i 's/a/b/' file.txt", expected: Mutating, firstWord: "sed"},
+		{name: "git commit", script: "git commit -m 'wip'", expected: Mutating, firstWord: "git"},
+		{name: "unknown command", script: "frobnicate --all", expected: Mutating, firstWord: "frobnicate"},
+		{name: "timeout wrapper", script: "timeout 10 ls", expected: ReadOnly, firstWord: "timeout"},
+		{name: "recursive delete", script: "rm -rf ~/project", expected: Destructive, firstWord: "rm"},
+		{name: "recursive delete long flag", script: "rm --recursive build", expected: Destructive, firstWord: "rm"},
+		{name: "dd", script: "dd if=/dev/zero of=disk.img bs=1M count=10", expected: Destructive, firstWord: "dd"},
+		{name: "mkfs", script: "mkfs.ext4 /dev/sdb1", expected: Destructive, firstWord: "mkfs.ext4"},
+		{name: "recursive chmod", script: "chmod -R 777 .", expected: Destructive, firstWord: "chmod"},
+		{name: "find delete", script: "find /tmp -name '*.tmp' -delete", expected: Destructive, firstWord: "find"},
+		{name: "find exec rm", script: `find . -name '*.bak' -exec rm {} \;`, expected: Destructive, firstWord: "find"},
+		{name: "xargs rm", script: "find . -name '*.o' | xargs rm -f", expected: Mutating, firstWord: "find"},
+		{name: "xargs rm recursive", script: "ls | xargs -I {} rm -r {}", expected: Destructive, firstWord: "ls"},
+		{name: "curl pipe to shell", script: "curl -fsSL https://example.com/install.sh | sh", expected: Destructive, firstWord: "curl"},
+		{name: "write to device", script: "cat image.iso > /dev/sdb", expected: Destructive, firstWord: "cat"},
+		{name: "overwrite system file", script: "echo nameserver 1.1.1.1 > /etc/resolv.conf", expected: Destructive, firstWord: "echo"},
+		{name: "git reset hard", script: "git reset --hard HEAD~1", expected: Destructive, firstWord: "git"},
+		{name: "bash -c inline", script: `bash -c "rm -rf build"`, expected: Destructive, firstWord: "bash"},
+		{name: "command substitution", script: "echo $(rm -rf /tmp/x)", expected: Destructive, firstWord: "echo"},
+		{name: "powershell recursive remove", script: "Remove-Item -Recurse -Force C:\\temp", expected: Destructive, firstWord: "Remove-Item"},
+		{name: "sudo", script: "sudo apt update", expected: Privileged, firstWord: "sudo"},
+		{name: "sudo with user", script: "sudo -u postgres psql -c 'select 1'", expected: Privileged, firstWord: "sudo"},
+		{name: "sudo destructive", script: "sudo rm -rf /var/log/app", expected: Privileged, firstWord: "sudo"},
+		{name: "curl pipe to sudo bash", script: "curl -s https://example.com/x | sudo bash", expected: Privileged, firstWord: "curl"},
+		{name: "ip show", script: "ip -4 addr show dev eth0", expected: ReadOnly, firstWord: "ip"},
+		{name: "ip route", script: "ip route", expected: ReadOnly, firstWord: "ip"},
+		{name: "ip link set", script: "ip link set eth0 down", expected: Mutating, firstWord: "ip"},
+		{name: "ip addr flush", script: "ip addr flush dev eth0", expected: Mutating, firstWord: "ip"},
+		{name: "ip route del", script: "ip route del default", expected: Mutating, firstWord: "ip"},
+		{name: "git branch list", script: "git branch -a --contains HEAD", expected: ReadOnly, firstWord: "git"},
+		{name: "git branch delete", script: "git branch -D main", expected: Mutating, firstWord: "git"},
+		{name: "git branch create", script: "git branch feature", expected: Mutating, firstWord: "git"},
+		{name: "git remote list", script: "git remote -v", expected: ReadOnly, firstWord: "git"},
+		{name: "git remote get-url", script: "git remote get-url origin", expected: ReadOnly, firstWord: "git"},
+		{name: "git remote remove", script: "git remote remove origin", expected: Mutating, firstWord: "git"},
+		{name: "git tag list", script: "git tag -l 'v1.*'", expected: ReadOnly, firstWord: "git"},
+		{name: "git tag delete", script: "git tag -d v1", expected: Mutating, firstWord: "git"},
+		{name: "date format", script: "date -u +%s", expected: ReadOnly, firstWord: "date"},
+		{name: "date set", script: "date -s '2024-01-01 12:00'", expected: Mutating, firstWord: "date"},
+		{name: "hostname show", script: "hostname -f", expected: ReadOnly, firstWord: "hostname"},
+		{name: "hostname set", script: "hostname foo", expected: Mutating, firstWord: "hostname"},
+		{name: "awk system", script: `awk 'BEGIN{system("rm -rf ~")}'`, expected: Mutating, firstWord: "awk"},
+		{name: "awk print to file", script: `awk '{print $1 > "out.txt"}' log`, expected: Mutating, firstWord: "awk"},
+		{name: "awk pipe to command", script: `awk '{print | "sh"}' cmds`, expected: Mutating, firstWord: "awk"},
+		{name: "awk comparison", script: `awk '$3 > 100 {print $1}' log`, expected: ReadOnly, firstWord: "awk"},
+		{name: "git config option", script: "git -c core.pager='rm -rf ~' log", expected: Mutating, firstWord: "git"},
+		{name: "git config option without subcommand", script: "git -c alias.x=y", expected: Mutating, firstWord: "git"},
+		{name: "find fprint", script: "find . -fprint /etc/passwd", expected: Mutating, firstWord: "find"},
+		{name: "find fls", script: "find . -name x -fls list.txt", expected: Mutating, firstWord: "find"},
+		{name: "tar long flag bundle", script: "tar -xzvpf a.tgz", expected: Mutating, firstWord: "tar"},
+		{name: "tar list", script: "tar -tzvf a.tgz", expected: ReadOnly, firstWord: "tar"},
+		{name: "bundled flags", script: "rm -rfv build", expected: Destructive, firstWord: "rm"},
+		{name: "powershell force remove", script: "Remove-Item -Force foo.txt", expected: Mutating, firstWord: "Remove-Item"},
+		{name: "unparsable script", script: "if then fi (", expected: Mutating, firstWord: "if"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assessment := Analyze(tt.script)
+
+			if assessment.Level != tt.expected {
+				t.Errorf("expected level %s but got %s (findings: %+v)", tt.expected, assessment.Level, assessment.Findings)
+			}
+			if assessment.FirstWord != tt.firstWord {
+				t.Errorf("expected first word %q but got %q", tt.firstWord, assessment.FirstWord)
+			}
+		})
+	}
+}
+
+func TestAnalyzeCommands(t *testing.T) {
+	assessment := Analyze("sudo -u app rm -rf /srv/app/cache > /tmp/log.txt && bash -c 'touch done'")
+
+	expected := []string{"sudo", "rm", "bash", "touch"}
+	if len(assessment.Commands) != len(expected) {
+		t.Fatalf("expected %d commands but got %+v", len(expected), assessment.Commands)
+	}
+	for i, name := range expected {
+		if assessment.Commands[i].Name != name {
+			t.Errorf("expected command %d to be %q but got %q", i, name, assessment.Commands[i].Name)
+		}
+	}
+	if len(assessment.Redirects) != 1 || assessment.Redirects[0] != "/tmp/log.txt" {
+		t.Errorf("expected redirect to /tmp/log.txt but got %v", assessment.Redirects)
+	}
+}
+
+func TestLevelString(t *testing.T) {
+	tests := []struct {
+		level    Level
+		expected string
+	}{
+		{level: ReadOnly, expected: "read-only"},
+		{level: Mutating, expected: "mutating"},
+		{level: Destructive, expected: "destructive"},
+		{level: Privileged, expected: "privileged"},
+		{level: Level(9), expected: "Level(9)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.level.String(); got != tt.expected {
+			t.Errorf("expected %q but got %q", tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package session stores chat sessions on disk so that they can be listed and resumed.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blysin/autocmdr/pkg/chat"
+)
+
+// ErrNotFound is returned when a session does not exist
+var ErrNotFound = errors.New("session not found")
+
+// validID restricts session IDs to characters that are safe in file names
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Store saves sessions as JSON files in a directory
+type Store struct {
+	dir string
+}
+
+// Summary describes a stored session without its full transcript
+type Summary struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Messages   int       `json:"messages"`
+	Executions int       `json:"executions"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// NewStore creates a store in the sessions directory under configDir
+func NewStore(configDir string) *Store {
+	return &Store{dir: filepath.Join(configDir, "sessions")}
+}
+
+// Dir returns the directory where sessions are stored
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// NewID generates a session ID from the current time and a random suffix
+func NewID() string {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return time.Now().Format("20060102-150405.000")
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// ValidateID checks that an ID can be used as a session file name
+func ValidateID(id string) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("invalid session id %q: use letters, digits, '.', '_' or '-'", id)
+	}
+	return nil
+}
+
+// Save writes the session to disk, replacing any previous version atomically
+func (s *Store) Save(_ context.Context, session *chat.Session) error {
+	if err := ValidateID(session.ID); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, session.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(session.ID)); err != nil {
+		return fmt.Errorf("failed to save session file: %w", err)
+	}
+	return nil
+}
+
+// Load reads a session and rebuilds its memory with the given options
+func (s *Store) Load(id string, options *chat.Options) (*chat.Session, error) {
+	if err := ValidateID(id); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	session := &chat.Session{}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("failed to decode session %s: %w", id, err)
+	}
+	session.ID = id
+	session.Restore(options)
+	return session, nil
+}
+
+// Latest loads the most recently updated session
+func (s *Store) Latest(options *chat.Options) (*chat.Session, error) {
+	summaries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, ErrNotFound
+	}
+	return s.Load(summaries[0].ID, options)
+}
+
+// List returns summaries of all sessions, most recently updated first
+func (s *Store) List() ([]Summary, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	var summaries []Summary
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		session, err := s.Load(id, nil)
+		if err != nil {
+			continue
+		}
+		summaries = append(summaries, summarize(session))
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+	return summaries, nil
+}
+
+// Delete removes a session
+func (s *Store) Delete(id string) error {
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// path returns the file path of a session
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// summarize builds a summary, using the first user message as the title
+func summarize(session *chat.Session) Summary {
+	summary := Summary{
+		ID:         session.ID,
+		Messages:   len(session.Messages),
+		Executions: len(session.Executions),
+		CreatedAt:  session.CreatedAt,
+		UpdatedAt:  session.UpdatedAt,
+	}
+	for _, msg := range session.Messages {
+		if msg.Role == chat.RoleHuman {
+			summary.Title = truncate(strings.Join(strings.Fields(msg.Content), " "), 60)
+			break
+		}
+	}
+	return summary
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
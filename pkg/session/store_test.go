@@ -0,0 +1,143 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blysin/autocmdr/pkg/chat"
+)
+
+func TestStoreSaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(t.TempDir())
+
+	s := chat.NewSession("test-session", chat.DefaultChatOptions())
+	s.AddMessage(chat.RoleHuman, "list files")
+	s.AddMessage(chat.RoleAI, `{"success": true, "multipleLines": false, "script": "ls"}`)
+	s.AddExecution(&chat.ExecutionResult{Success: true, Command: "ls", Output: "a\nb\n"})
+
+	if err := store.Save(ctx, s); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+
+	loaded, err := store.Load("test-session", chat.DefaultChatOptions())
+	if err != nil {
+		t.Fatalf("failed to load session: %v", err)
+	}
+
+	if len(loaded.Messages) != 2 || loaded.Messages[0].Content != "list files" {
+		t.Errorf("unexpected messages: %+v", loaded.Messages)
+	}
+	if len(loaded.Executions) != 1 || loaded.LastExec == nil || loaded.LastExec.Output != "a\nb\n" {
+		t.Errorf("unexpected executions: %+v", loaded.Executions)
+	}
+
+	vars, err := loaded.Memory.LoadMemoryVariables(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to load memory: %v", err)
+	}
+	if history, _ := vars["history"].(string); history == "" {
+		t.Error("expected memory to be restored from the transcript")
+	}
+}
+
+func TestStoreRestoreWindow(t *testing.T) {
+	options := &chat.Options{MemorySize: 2}
+	s := chat.NewSession("window", options)
+	for i := 0; i < 5; i++ {
+		s.AddMessage(chat.RoleHuman, "question")
+		s.AddMessage(chat.RoleAI, "answer")
+	}
+
+	s.Restore(options)
+	vars, err := s.Memory.LoadMemoryVariables(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to load memory: %v", err)
+	}
+	history, _ := vars["history"].(string)
+	if got := len(strings.Split(strings.TrimSpace(history), "\n")); got != 4 {
+		t.Errorf("expected 4 messages in memory but got %d", got)
+	}
+	if len(s.Messages) != 10 {
+		t.Errorf("expected full transcript to be kept but got %d messages", len(s.Messages))
+	}
+}
+
+func TestStoreListAndLatest(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(t.TempDir())
+
+	older := chat.NewSession("older", nil)
+	older.AddMessage(chat.RoleHuman, "first request")
+	older.UpdatedAt = time.Now().Add(-time.Hour)
+	newer := chat.NewSession("newer", nil)
+	newer.AddMessage(chat.RoleHuman, "second request")
+
+	for _, s := range []*chat.Session{older, newer} {
+		if err := store.Save(ctx, s); err != nil {
+			t.Fatalf("failed to save session: %v", err)
+		}
+	}
+
+	summaries, err := store.List()
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(summaries) != 2 || summaries[0].ID != "newer" || summaries[1].Title != "first request" {
+		t.Errorf("unexpected summaries: %+v", summaries)
+	}
+
+	latest, err := store.Latest(nil)
+	if err != nil {
+		t.Fatalf("failed to load latest session: %v", err)
+	}
+	if latest.ID != "newer" {
+		t.Errorf("expected latest session %q but got %q", "newer", latest.ID)
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(t.TempDir())
+
+	if err := store.Save(ctx, chat.NewSession("doomed", nil)); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+	if err := store.Delete("doomed"); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	if _, err := store.Load("doomed", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+	if err := store.Delete("doomed"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+}
+
+func TestValidateID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{id: "20261017-150405-ab12"},
+		{id: "debug.nginx_1"},
+		{id: "", wantErr: true},
+		{id: "../etc/passwd", wantErr: true},
+		{id: "a/b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			err := ValidateID(tt.id)
+			if tt.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+// Package sysinfo collects facts about the machine and the working directory, such as the shell,
+// the package managers and the type of project, so that the model can write scripts that fit them.
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Collector names
+const (
+	CWD             = "cwd"
+	Shell           = "shell"
+	User            = "user"
+	PackageManagers = "package_managers"
+	Project         = "project"
+	Git             = "git"
+	Tools           = "tools"
+)
+
+// Names lists the collectors in the order their facts are shown
+var Names = []string{CWD, Shell, User, PackageManagers, Project, Git, Tools}
+
+// commandTimeout bounds each command a collector runs, so that a hanging tool cannot delay startup
+const commandTimeout = 2 * time.Second
+
+// Fact is a piece of information about the environment
+type Fact struct {
+	Label string
+	Value string
+}
+
+// Options configures the collectors
+type Options struct {
+	// Dir is the working directory; the current directory is used when empty
+	Dir string
+	// Shell is the shell that runs scripts; it is detected from $SHELL when empty
+	Shell string
+}
+
+// collectFunc gathers the facts of one collector; it returns nothing when it has nothing to report
+type collectFunc func(ctx context.Context, options *Options) []Fact
+
+// collectors maps collector names to the functions gathering their facts
+var collectors = map[string]collectFunc{
+	CWD:             collectCWD,
+	Shell:           collectShell,
+	User:            collectUser,
+	PackageManagers: collectPackageManagers,
+	Project:         collectProject,
+	Git:             collectGit,
+	Tools:           collectTools,
+}
+
+// Known reports whether name is a collector
+func Known(name string) bool {
+	_, ok := collectors[name]
+	return ok
+}
+
+// Collect runs the named collectors concurrently and returns their facts in the order of names
+func Collect(ctx context.Context, names []string, options *Options) ([]Fact, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	resolved := *options
+	if resolved.Dir == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		resolved.Dir = dir
+	}
+
+	funcs := make([]collectFunc, len(names))
+	for i, name := range names {
+		collect, ok := collectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown context collector: %s (available: %s)", name, strings.Join(Names, ", "))
+		}
+		funcs[i] = collect
+	}
+
+	results := make([][]Fact, len(funcs))
+	var wg sync.WaitGroup
+	for i, collect := range funcs {
+		wg.Add(1)
+		go func(i int, collect collectFunc) {
+			defer wg.Done()
+			results[i] = collect(ctx, &resolved)
+		}(i, collect)
+	}
+	wg.Wait()
+
+	var facts []Fact
+	for _, result := range results {
+		facts = append(facts, result...)
+	}
+	return facts, nil
+}
+
+// Format renders facts as a list, one per line
+func Format(facts []Fact) string {
+	var b strings.Builder
+	for _, fact := range facts {
+		fmt.Fprintf(&b, "- %s: %s\n", fact.Label, fact.Value)
+	}
+	return b.String()
+}
+
+// collectCWD reports the working directory
+func collectCWD(_ context.Context, options *Options) []Fact {
+	return []Fact{{Label: "Working directory", Value: options.Dir}}
+}
+
+// collectShell reports the shell that runs scripts and its version
+func collectShell(ctx context.Context, options *Options) []Fact {
+	shell := options.Shell
+	if shell == "" {
+		shell = filepath.Base(os.Getenv("SHELL"))
+	}
+	if shell == "" || shell == "." {
+		return nil
+	}
+
+	switch shell {
+	case "bash", "zsh", "fish", "pwsh":
+		if version := firstLine(run(ctx, shell, "--version")); version != "" {
+			return []Fact{{Label: "Shell", Value: fmt.Sprintf("%s (%s)", shell, version)}}
+		}
+	case "powershell":
+		if version := firstLine(run(ctx, shell, "-NoProfile", "-Command", "$PSVersionTable.PSVersion.ToString()")); version != "" {
+			return []Fact{{Label: "Shell", Value: fmt.Sprintf("%s %s", shell, version)}}
+		}
+	}
+	return []Fact{{Label: "Shell", Value: shell}}
+}
+
+// collectUser reports the user name and whether scripts run as root
+func collectUser(_ context.Context, _ *Options) []Fact {
+	name := "unknown"
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+
+	switch {
+	case runtime.GOOS == "windows":
+		return []Fact{{Label: "User", Value: name}}
+	case os.Geteuid() == 0:
+		return []Fact{{Label: "User", Value: name + ", running as root, sudo is not needed"}}
+	case lookPath("sudo") != "":
+		return []Fact{{Label: "User", Value: name + ", not root, sudo is available"}}
+	default:
+		return []Fact{{Label: "User", Value: name + ", not root, sudo is not installed"}}
+	}
+}
+
+// packageManagers are the system package managers looked up in PATH
+var packageManagers = []string{"apt", "dnf", "yum", "zypper", "pacman", "apk", "brew", "port", "snap", "flatpak", "nix", "winget", "choco", "scoop"}
+
+// collectPackageManagers reports the package managers found in PATH
+func collectPackageManagers(_ context.Context, _ *Options) []Fact {
+	var found []string
+	for _, name := range packageManagers {
+		if lookPath(name) != "" {
+			found = append(found, name)
+		}
+	}
+	if len(found) == 0 {
+		return []Fact{{Label: "Package managers", Value: "none found"}}
+	}
+	return []Fact{{Label: "Package managers", Value: strings.Join(found, ", ")}}
+}
+
+// projectMarkers map files found in a project directory to the kind of project they indicate
+var projectMarkers = []struct {
+	file string
+	kind string
+}{
+	{file: "go.mod", kind: "Go"},
+	{file: "Cargo.toml", kind: "Rust"},
+	{file: "package.json", kind: "Node.js"},
+	{file: "pyproject.toml", kind: "Python"},
+	{file: "requirements.txt", kind: "Python"},
+	{file: "setup.py", kind: "Python"},
+	{file: "pom.xml", kind: "Java (Maven)"},
+	{file: "build.gradle", kind: "Java (Gradle)"},
+	{file: "build.gradle.kts", kind: "Kotlin (Gradle)"},
+	{file: "Gemfile", kind: "Ruby"},
+	{file: "composer.json", kind: "PHP"},
+	{file: "CMakeLists.txt", kind: "C/C++ (CMake)"},
+	{file: "Makefile", kind: "Make"},
+	{file: "Dockerfile", kind: "Docker"},
+}
+
+// nodeLockFiles tell which package manager a Node.js project uses
+var nodeLockFiles = []struct {
+	file    string
+	manager string
+}{
+	{file: "pnpm-lock.yaml", manager: "pnpm"},
+	{file: "yarn.lock", manager: "yarn"},
+	{file: "bun.lockb", manager: "bun"},
+	{file: "package-lock.json", manager: "npm"},
+}
+
+// collectProject reports the kind of project in the nearest directory with a project file,
+// searching up from the working directory but not past the root of a git repository
+func collectProject(_ context.Context, options *Options) []Fact {
+	for dir := options.Dir; ; {
+		if kinds := projectKinds(dir); len(kinds) > 0 {
+			value := strings.Join(kinds, ", ")
+			if dir != options.Dir {
+				value += " in " + dir
+			}
+			return []Fact{{Label: "Project", Value: value}}
+		}
+
+		parent := filepath.Dir(dir)
+		if exists(filepath.Join(dir, ".git")) || parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// projectKinds lists the kinds of project whose files are in dir
+func projectKinds(dir string) []string {
+	var kinds []string
+	seen := map[string]bool{}
+	for _, marker := range projectMarkers {
+		if seen[marker.kind] || !exists(filepath.Join(dir, marker.file)) {
+			continue
+		}
+		seen[marker.kind] = true
+
+		kind := fmt.Sprintf("%s (%s)", marker.kind, marker.file)
+		if marker.file == "package.json" {
+			manager := "npm"
+			for _, lock := range nodeLockFiles {
+				if exists(filepath.Join(dir, lock.file)) {
+					manager = lock.manager
+					break
+				}
+			}
+			kind = fmt.Sprintf("%s (%s, %s)", marker.kind, marker.file, manager)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+// collectGit reports the branch of the git repository and whether it has uncommitted changes
+func collectGit(ctx context.Context, options *Options) []Fact {
+	if lookPath("git") == "" {
+		return nil
+	}
+	status, err := output(ctx, "git", "-C", options.Dir, "status", "--porcelain", "--branch")
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimRight(status, "\n"), "\n")
+	branch := branchName(strings.TrimPrefix(lines[0], "## "))
+	changed := 0
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) != "" {
+			changed++
+		}
+	}
+	state := "clean"
+	if changed > 0 {
+		state = fmt.Sprintf("%d uncommitted changes", changed)
+	}
+	return []Fact{{Label: "Git", Value: fmt.Sprintf("branch %s, %s", branch, state)}}
+}
+
+// branchName describes the branch from the header of git status --branch,
+// such as "main...origin/main [ahead 1]" or "No commits yet on main"
+func branchName(header string) string {
+	switch {
+	case strings.HasPrefix(header, "HEAD (no branch)"):
+		return "detached HEAD"
+	case strings.HasPrefix(header, "No commits yet on "):
+		return strings.TrimPrefix(header, "No commits yet on ") + " (no commits yet)"
+	}
+	branch, tracking, _ := strings.Cut(header, "...")
+	if _, ahead, ok := strings.Cut(tracking, " ["); ok {
+		return fmt.Sprintf("%s (%s)", branch, strings.TrimSuffix(ahead, "]"))
+	}
+	return branch
+}
+
+// versionCommands print the version of common development tools
+var versionCommands = []struct {
+	name string
+	args []string
+}{
+	{name: "git", args: []string{"--version"}},
+	{name: "go", args: []string{"version"}},
+	{name: "node", args: []string{"--version"}},
+	{name: "npm", args: []string{"--version"}},
+	{name: "python3", args: []string{"--version"}},
+	{name: "cargo", args: []string{"--version"}},
+	{name: "java", args: []string{"-version"}},
+	{name: "docker", args: []string{"--version"}},
+	{name: "kubectl", args: []string{"version", "--client"}},
+	{name: "make", args: []string{"--version"}},
+}
+
+// collectTools reports the versions of the development tools found in PATH
+func collectTools(ctx context.Context, _ *Options) []Fact {
+	versions := make([]string, len(versionCommands))
+	var wg sync.WaitGroup
+	for i, command := range versionCommands {
+		if lookPath(command.name) == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string, args []string) {
+			defer wg.Done()
+			version := firstLine(run(ctx, name, args...))
+			if version == "" {
+				version = "installed"
+			}
+			versions[i] = fmt.Sprintf("%s (%s)", name, version)
+		}(i, command.name, command.args)
+	}
+	wg.Wait()
+
+	var found []string
+	for _, version := range versions {
+		if version != "" {
+			found = append(found, version)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	return []Fact{{Label: "Tools", Value: strings.Join(found, "; ")}}
+}
+
+// run runs a command with a timeout and returns its combined output, or nothing if it fails
+func run(ctx context.Context, name string, args ...string) string {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// output runs a command with a timeout and returns its standard output
+func output(ctx context.Context, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	return string(out), err
+}
+
+// firstLine returns the first non-empty line of s, shortened for the prompt
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if len(line) > 80 {
+				line = line[:80] + "..."
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+// lookPath returns the path of an executable in PATH, or an empty string
+func lookPath(name string) string {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// exists reports whether path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
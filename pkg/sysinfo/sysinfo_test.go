@@ -0,0 +1,152 @@
+package sysinfo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCollectProject(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		dir   string
+		want  string
+	}{
+		{
+			name:  "go module",
+			files: []string{"go.mod", "Makefile"},
+			want:  "Go (go.mod), Make (Makefile)",
+		},
+		{
+			name:  "node with pnpm",
+			files: []string{"package.json", "pnpm-lock.yaml"},
+			want:  "Node.js (package.json, pnpm)",
+		},
+		{
+			name:  "python listed once",
+			files: []string{"pyproject.toml", "requirements.txt"},
+			want:  "Python (pyproject.toml)",
+		},
+		{
+			name:  "found in a parent directory",
+			files: []string{"Cargo.toml", "src/main.rs"},
+			dir:   "src",
+			want:  "Rust (Cargo.toml) in ",
+		},
+		{
+			name:  "not searched past the repository root",
+			files: []string{"go.mod", "repo/.git/HEAD", "repo/docs/index.md"},
+			dir:   "repo/docs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files...)
+
+			facts := collectProject(context.Background(), &Options{Dir: filepath.Join(root, tt.dir)})
+			if tt.want == "" {
+				if len(facts) != 0 {
+					t.Errorf("collectProject() = %v, want no facts", facts)
+				}
+				return
+			}
+			if len(facts) != 1 || !strings.HasPrefix(facts[0].Value, tt.want) {
+				t.Errorf("collectProject() = %v, want a value starting with %q", facts, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "-C", dir, "init", "-q", "-b", "main").CombinedOutput(); err != nil {
+		t.Skipf("git init failed: %s", out)
+	}
+
+	facts := collectGit(context.Background(), &Options{Dir: dir})
+	if len(facts) != 1 || facts[0].Value != "branch main (no commits yet), clean" {
+		t.Errorf("collectGit() = %v, want a clean main branch", facts)
+	}
+
+	writeFiles(t, dir, "a.txt", "b.txt")
+	facts = collectGit(context.Background(), &Options{Dir: dir})
+	if len(facts) != 1 || facts[0].Value != "branch main (no commits yet), 2 uncommitted changes" {
+		t.Errorf("collectGit() = %v, want 2 uncommitted changes", facts)
+	}
+
+	if facts := collectGit(context.Background(), &Options{Dir: t.TempDir()}); len(facts) != 0 {
+		t.Errorf("collectGit() outside a repository = %v, want no facts", facts)
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	tests := map[string]string{
+		"main":                         "main",
+		"main...origin/main":           "main",
+		"main...origin/main [ahead 2]": "main (ahead 2)",
+		"dev...origin/dev [behind 1]":  "dev (behind 1)",
+		"HEAD (no branch)":             "detached HEAD",
+		"No commits yet on trunk":      "trunk (no commits yet)",
+	}
+	for header, want := range tests {
+		if got := branchName(header); got != want {
+			t.Errorf("branchName(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "go.mod")
+
+	facts, err := Collect(context.Background(), []string{Project, CWD, Shell}, &Options{Dir: dir, Shell: "sh"})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	want := "- Project: Go (go.mod)\n- Working directory: " + dir + "\n- Shell: sh\n"
+	if got := Format(facts); got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+
+	if _, err := Collect(context.Background(), []string{CWD, "weather"}, nil); err == nil || !strings.Contains(err.Error(), "unknown context collector: weather") {
+		t.Errorf("Collect() error = %v, want an unknown collector error", err)
+	}
+
+	facts, err = Collect(context.Background(), nil, nil)
+	if err != nil || len(facts) != 0 {
+		t.Errorf("Collect() with no collectors = %v, %v", facts, err)
+	}
+}
+
+func TestKnown(t *testing.T) {
+	for _, name := range Names {
+		if !Known(name) {
+			t.Errorf("Known(%q) = false", name)
+		}
+	}
+	if Known("weather") {
+		t.Error(`Known("weather") = true`)
+	}
+}
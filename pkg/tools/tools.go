@@ -0,0 +1,385 @@
+// Package tools provides read-only functions the model can call to inspect the system
+// before it proposes a script, so that scripts match the actual machine.
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxOutputBytes caps the output of a tool sent back to the model
+const MaxOutputBytes = 8 << 10
+
+// Limits on what the tools read
+const (
+	maxEntries     = 200
+	defaultLines   = 20
+	maxLines       = 200
+	commandTimeout = 5 * time.Second
+)
+
+// Tool is a read-only function the model can call
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON Schema of the arguments
+	Parameters map[string]interface{}
+	run        func(ctx context.Context, args arguments) (string, error)
+}
+
+// Call runs the tool with arguments encoded as a JSON object and returns its output, capped at MaxOutputBytes
+func (t *Tool) Call(ctx context.Context, rawArgs string) (string, error) {
+	args := arguments{}
+	if strings.TrimSpace(rawArgs) != "" {
+		if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments for %s: %w", t.Name, err)
+		}
+	}
+
+	output, err := t.run(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	if len(output) > MaxOutputBytes {
+		output = output[:MaxOutputBytes] + "\n... (truncated)"
+	}
+	return output, nil
+}
+
+// Find returns the tool with the given name, or nil
+func Find(tools []*Tool, name string) *Tool {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+// ReadOnly returns the tools that inspect the system without changing it
+func ReadOnly() []*Tool {
+	return []*Tool{
+		{
+			Name:        "list_directory",
+			Description: "List the entries of a directory with their type and size",
+			Parameters:  object(map[string]interface{}{"path": stringParam("Directory to list, defaults to the current directory")}),
+			run:         listDirectory,
+		},
+		{
+			Name:        "read_file_head",
+			Description: "Read the first lines of a text file",
+			Parameters: object(map[string]interface{}{
+				"path":  stringParam("File to read"),
+				"lines": map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Number of lines to read, at most %d", maxLines)},
+			}, "path"),
+			run: readFileHead,
+		},
+		{
+			Name:        "which",
+			Description: "Find the path of an executable in PATH",
+			Parameters:  object(map[string]interface{}{"name": stringParam("Name of the executable")}, "name"),
+			run:         which,
+		},
+		{
+			Name:        "package_installed",
+			Description: "Check whether a package is installed with the system package manager",
+			Parameters:  object(map[string]interface{}{"name": stringParam("Name of the package")}, "name"),
+			run:         packageInstalled,
+		},
+		{
+			Name:        "env_var_names",
+			Description: "List the names of the environment variables, without their values",
+			Parameters:  object(map[string]interface{}{}),
+			run:         envVarNames,
+		},
+		{
+			Name:        "git_status",
+			Description: "Show the branch and changed files of a git repository",
+			Parameters:  object(map[string]interface{}{"path": stringParam("Directory inside the repository, defaults to the current directory")}),
+			run:         gitStatus,
+		},
+	}
+}
+
+// arguments are the decoded arguments of a tool call
+type arguments map[string]interface{}
+
+// string returns the string argument name, or def if it is missing or empty
+func (a arguments) string(name, def string) string {
+	if value, ok := a[name].(string); ok && strings.TrimSpace(value) != "" {
+		return strings.TrimSpace(value)
+	}
+	return def
+}
+
+// int returns the integer argument name, or def if it is missing
+func (a arguments) int(name string, def int) int {
+	switch value := a[name].(type) {
+	case float64:
+		return int(value)
+	case string:
+		var n int
+		if _, err := fmt.Sscan(value, &n); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// object returns the JSON Schema of an object with the given properties
+func object(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// stringParam returns the JSON Schema of a string parameter
+func stringParam(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+// listDirectory lists a directory, marking directories with a trailing slash
+func listDirectory(_ context.Context, args arguments) (string, error) {
+	path := expandHome(args.string("path", "."))
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to list directory: %w", err)
+	}
+	if len(entries) == 0 {
+		return "(empty directory)", nil
+	}
+
+	var b strings.Builder
+	for i, entry := range entries {
+		if i == maxEntries {
+			fmt.Fprintf(&b, "... and %d more entries\n", len(entries)-maxEntries)
+			break
+		}
+		info, err := entry.Info()
+		switch {
+		case err != nil:
+			fmt.Fprintf(&b, "%s\n", entry.Name())
+		case entry.IsDir():
+			fmt.Fprintf(&b, "%s/\n", entry.Name())
+		case info.Mode()&os.ModeSymlink != 0:
+			target, _ := os.Readlink(filepath.Join(path, entry.Name()))
+			fmt.Fprintf(&b, "%s -> %s\n", entry.Name(), target)
+		default:
+			fmt.Fprintf(&b, "%s\t%d bytes\n", entry.Name(), info.Size())
+		}
+	}
+	return b.String(), nil
+}
+
+// readFileHead reads the first lines of a file, refusing files that usually hold credentials
+func readFileHead(_ context.Context, args arguments) (string, error) {
+	path := args.string("path", "")
+	if path == "" {
+		return "", fmt.Errorf("missing path")
+	}
+	path = expandHome(path)
+	if sensitive(path) {
+		return "", fmt.Errorf("refusing to read %s: it may contain credentials", path)
+	}
+	// A symlink to a credentials file is refused as well
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		if sensitive(target) {
+			return "", fmt.Errorf("refusing to read %s: it may contain credentials", path)
+		}
+		path = target
+	}
+	// Reading a FIFO or device could block forever
+	if info, err := os.Stat(path); err == nil && !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", path)
+	}
+
+	lines := args.int("lines", defaultLines)
+	if lines <= 0 {
+		lines = defaultLines
+	}
+	if lines > maxLines {
+		lines = maxLines
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), MaxOutputBytes)
+	for n := 0; n < lines && scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.ContainsRune(line, 0) {
+			return "", fmt.Errorf("%s is not a text file", path)
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if b.Len() == 0 {
+		return "(empty file)", nil
+	}
+	return b.String(), nil
+}
+
+// which finds an executable in PATH
+func which(_ context.Context, args arguments) (string, error) {
+	name := args.string("name", "")
+	if name == "" {
+		return "", fmt.Errorf("missing name")
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Sprintf("%s not found in PATH", name), nil
+	}
+	return path, nil
+}
+
+// packageManagers are the commands that query whether a package is installed, in the order they are tried
+var packageManagers = []struct {
+	binary string
+	args   []string
+}{
+	{binary: "dpkg-query", args: []string{"-W", "-f=${Status} ${Version}\n"}},
+	{binary: "rpm", args: []string{"-q"}},
+	{binary: "pacman", args: []string{"-Q"}},
+	{binary: "apk", args: []string{"info", "-e", "-v"}},
+	{binary: "brew", args: []string{"list", "--versions"}},
+}
+
+// packageName matches valid package names, which must not start with a dash so that they cannot be read as options
+var packageName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+._@:/-]*$`)
+
+// packageInstalled asks the first available package manager whether a package is installed
+func packageInstalled(ctx context.Context, args arguments) (string, error) {
+	name := args.string("name", "")
+	if name == "" {
+		return "", fmt.Errorf("missing name")
+	}
+	if !packageName.MatchString(name) {
+		return "", fmt.Errorf("invalid package name: %q", name)
+	}
+
+	for _, manager := range packageManagers {
+		if _, err := exec.LookPath(manager.binary); err != nil {
+			continue
+		}
+		output, err := run(ctx, manager.binary, append(append([]string{}, manager.args...), name)...)
+		output = strings.TrimSpace(output)
+		if err != nil || output == "" || strings.Contains(output, "not-installed") || strings.Contains(output, "deinstall") {
+			return fmt.Sprintf("%s is not installed (checked with %s)", name, manager.binary), nil
+		}
+		return fmt.Sprintf("%s is installed (checked with %s): %s", name, manager.binary, output), nil
+	}
+	return "no supported package manager found", nil
+}
+
+// envVarNames lists the names of the environment variables, leaving out their values
+func envVarNames(_ context.Context, _ arguments) (string, error) {
+	names := make([]string, 0, len(os.Environ()))
+	for _, entry := range os.Environ() {
+		if name, _, ok := strings.Cut(entry, "="); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, "\n"), nil
+}
+
+// gitStatus shows the short status of the repository containing path
+func gitStatus(ctx context.Context, args arguments) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "git is not installed", nil
+	}
+	dir := expandHome(args.string("path", "."))
+	gitArgs := append([]string{"-C", dir, "--no-pager"}, safeGitConfig(ctx, dir)...)
+	gitArgs = append(gitArgs, "status", "--short", "--branch", "--ignore-submodules=all")
+	output, err := run(ctx, "git", gitArgs...)
+	if err != nil {
+		return "", fmt.Errorf("git status failed: %s", strings.TrimSpace(output))
+	}
+	return output, nil
+}
+
+// safeGitConfig returns -c options that stop git status from running programs named in the configuration
+// of the repository: the fsmonitor, the hooks run when the index is refreshed, and the clean filters
+func safeGitConfig(ctx context.Context, dir string) []string {
+	options := []string{"-c", "core.fsmonitor=false", "-c", "core.hooksPath=" + os.DevNull}
+	// Reading the configuration does not run anything, and fails when no filter is set
+	filters, _ := run(ctx, "git", "-C", dir, "config", "--name-only", "--get-regexp", `^filter\.`)
+	seen := make(map[string]bool)
+	for _, key := range strings.Fields(filters) {
+		key = strings.TrimPrefix(key, "filter.")
+		name := key[:max(strings.LastIndexByte(key, '.'), 0)]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		options = append(options, "-c", "filter."+name+".clean=", "-c", "filter."+name+".process=")
+	}
+	return options
+}
+
+// run runs a command with a timeout and returns its combined output
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	return string(output), err
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// sensitiveDirs and sensitiveNames match files that usually hold credentials
+var (
+	sensitiveDirs  = []string{".ssh", ".gnupg", ".aws", ".kube", ".docker", ".autocmdr"}
+	sensitiveNames = []string{"id_*", "*.pem", "*.key", "*.p12", "*.pfx", ".env", ".env.*", ".netrc", ".git-credentials", ".pgpass", "credentials*", "shadow", "gshadow"}
+)
+
+// sensitive reports whether path is likely to hold credentials
+func sensitive(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	for _, part := range strings.Split(filepath.ToSlash(abs), "/") {
+		for _, dir := range sensitiveDirs {
+			if part == dir {
+				return true
+			}
+		}
+	}
+	base := strings.ToLower(filepath.Base(abs))
+	for _, pattern := range sensitiveNames {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
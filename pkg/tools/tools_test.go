@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	names := map[string]bool{}
+	for _, tool := range ReadOnly() {
+		if names[tool.Name] {
+			t.Errorf("duplicate tool %s", tool.Name)
+		}
+		names[tool.Name] = true
+		if tool.Description == "" || tool.Parameters["type"] != "object" {
+			t.Errorf("tool %s has no description or object parameters", tool.Name)
+		}
+	}
+	for _, name := range []string{"list_directory", "read_file_head", "which", "package_installed", "env_var_names", "git_status"} {
+		if Find(ReadOnly(), name) == nil {
+			t.Errorf("missing tool %s", name)
+		}
+	}
+	if Find(ReadOnly(), "rm") != nil {
+		t.Error("Find() returned a tool that does not exist")
+	}
+}
+
+func TestCall(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("one\ntwo\nthree\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "server.pem"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "server.pem"), filepath.Join(dir, "notes.link")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AUTOCMDR_TEST_SECRET", "hunter2")
+
+	tests := []struct {
+		name     string
+		tool     string
+		args     string
+		want     []string
+		dontWant []string
+		wantErr  string
+	}{
+		{
+			name: "list directory",
+			tool: "list_directory",
+			args: `{"path": "` + dir + `"}`,
+			want: []string{"notes.txt\t14 bytes", "sub/"},
+		},
+		{
+			name: "read file head",
+			tool: "read_file_head",
+			args: `{"path": "` + filepath.Join(dir, "notes.txt") + `", "lines": 2}`,
+			want: []string{"one\ntwo\n"}, dontWant: []string{"three"},
+		},
+		{
+			name:    "refuse credentials",
+			tool:    "read_file_head",
+			args:    `{"path": "` + filepath.Join(dir, "server.pem") + `"}`,
+			wantErr: "may contain credentials",
+		},
+		{
+			name:    "refuse symlink to credentials",
+			tool:    "read_file_head",
+			args:    `{"path": "` + filepath.Join(dir, "notes.link") + `"}`,
+			wantErr: "may contain credentials",
+		},
+		{
+			name:    "refuse directory",
+			tool:    "read_file_head",
+			args:    `{"path": "` + filepath.Join(dir, "sub") + `"}`,
+			wantErr: "not a regular file",
+		},
+		{
+			name:    "missing path",
+			tool:    "read_file_head",
+			args:    `{}`,
+			wantErr: "missing path",
+		},
+		{
+			name: "which",
+			tool: "which",
+			args: `{"name": "sh"}`,
+			want: []string{"/sh"},
+		},
+		{
+			name: "which missing",
+			tool: "which",
+			args: `{"name": "autocmdr-no-such-binary"}`,
+			want: []string{"not found in PATH"},
+		},
+		{
+			name: "package name",
+			tool: "package_installed",
+			args: `{"name": "autocmdr-no-such-package++"}`,
+		},
+		{
+			name:    "package name read as an option",
+			tool:    "package_installed",
+			args:    `{"name": "--eval=%(whoami)"}`,
+			wantErr: "invalid package name",
+		},
+		{
+			name:    "package name with shell characters",
+			tool:    "package_installed",
+			args:    `{"name": "curl; id"}`,
+			wantErr: "invalid package name",
+		},
+		{
+			name: "env var names",
+			tool: "env_var_names",
+			want: []string{"AUTOCMDR_TEST_SECRET"}, dontWant: []string{"hunter2"},
+		},
+		{
+			name:    "invalid arguments",
+			tool:    "which",
+			args:    `{"name":`,
+			wantErr: "invalid arguments for which",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Find(ReadOnly(), tt.tool).Call(context.Background(), tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Call() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Call() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("Call() = %q, want it to contain %q", output, want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(output, dontWant) {
+					t.Errorf("Call() = %q, want it not to contain %q", output, dontWant)
+				}
+			}
+		})
+	}
+}
+
+func TestSensitive(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/home/user/.ssh/config", want: true},
+		{path: "/home/user/.ssh", want: true},
+		{path: "/home/user/.autocmdr/config.json", want: true},
+		{path: "/project/.env", want: true},
+		{path: "/project/.env.local", want: true},
+		{path: "/home/user/id_ed25519.pub", want: true},
+		{path: "/etc/ssl/private/server.KEY", want: true},
+		{path: "/etc/shadow", want: true},
+		{path: "/home/user/.git-credentials", want: true},
+		{path: "/home/user/.netrc", want: true},
+		{path: "/home/user/.aws/credentials", want: true},
+		{path: "/project/README.md", want: false},
+		{path: "/project/environment.go", want: false},
+		{path: "/etc/hosts", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := sensitive(tt.path); got != tt.want {
+			t.Errorf("sensitive(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCallTruncates(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < maxEntries+5; i++ {
+		name := filepath.Join(dir, strings.Repeat("f", 40)+string(rune('a'+i%26))+strings.Repeat("x", i/26))
+		if err := os.WriteFile(name, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output, err := Find(ReadOnly(), "list_directory").Call(context.Background(), `{"path": "`+dir+`"}`)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if len(output) > MaxOutputBytes+len("\n... (truncated)") {
+		t.Errorf("Call() returned %d bytes, want at most %d", len(output), MaxOutputBytes)
+	}
+	if !strings.HasSuffix(output, "(truncated)") {
+		t.Errorf("Call() = %q, want a truncation marker", output[len(output)-40:])
+	}
+}
+
+func TestGitStatusIgnoresConfiguredPrograms(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil || runtime.GOOS == "windows" {
+		t.Skip("git and sh are needed")
+	}
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	program := `sh -c "echo $0 >> ` + marker + `; cat"`
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+	git("init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte("*.txt filter=evil\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("one\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	git("config", "core.fsmonitor", program)
+	git("config", "filter.evil.clean", program)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("two\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := Find(ReadOnly(), "git_status").Call(context.Background(), `{"path": "`+dir+`"}`)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if !strings.Contains(output, "notes.txt") {
+		t.Errorf("Call() = %q, want the modified file", output)
+	}
+	if ran, err := os.ReadFile(marker); err == nil {
+		t.Errorf("git status ran configured programs: %q", ran)
+	}
+}
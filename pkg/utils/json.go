@@ -4,50 +4,144 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 )
 
-// ExtractFirstJSON extracts the first valid JSON object from a string
+// thinkEnd closes the reasoning block some models emit before their answer
+const thinkEnd = "</think>"
+
+// fencedBlock matches Markdown code blocks, capturing their language tag and content
+var fencedBlock = regexp.MustCompile("(?s)```([A-Za-z]*)[ \t]*\r?\n(.*?)```")
+
+// ExtractFirstJSON extracts a valid JSON object from a model response.
+// Text before a closing </think> tag is ignored. Objects in fenced json code blocks are preferred;
+// otherwise the first valid object is returned, or the last one when the response has a reasoning block.
+// Braces inside JSON strings are skipped, and candidates that are not valid JSON are passed over.
 func ExtractFirstJSON(input string) (string, error) {
+	object, _, err := extractJSON(input, false)
+	return object, err
+}
+
+// ExtractRepairedJSON extracts a JSON object from a model response like ExtractFirstJSON, but also
+// accepts near-miss objects that RepairJSON can fix. It returns the object and the repairs applied to it.
+func ExtractRepairedJSON(input string) (string, []string, error) {
+	return extractJSON(input, true)
+}
+
+// candidate is a JSON object found in a response, with the repairs that made it valid
+type candidate struct {
+	object  string
+	repairs []string
+}
+
+// extractJSON implements ExtractFirstJSON and, when repair is set, ExtractRepairedJSON
+func extractJSON(input string, repair bool) (string, []string, error) {
 	if input == "" {
-		return "", fmt.Errorf("input string is empty")
+		return "", nil, fmt.Errorf("input string is empty")
 	}
 
-	var stack []rune
-	var start, end int
-	found := false
+	text := input
+	thinking := false
+	if idx := strings.LastIndex(text, thinkEnd); idx != -1 {
+		text = text[idx+len(thinkEnd):]
+		thinking = true
+	}
 
-	for i, ch := range input {
-		switch ch {
-		case '{':
-			if len(stack) == 0 {
-				start = i // Record JSON start position
+	for _, match := range fencedBlock.FindAllStringSubmatch(text, -1) {
+		if tag := strings.ToLower(match[1]); tag != "json" && tag != "" {
+			continue
+		}
+		if objects := jsonObjects(match[2], true, repair); len(objects) > 0 {
+			return objects[0].object, objects[0].repairs, nil
+		}
+	}
+
+	objects := jsonObjects(text, !thinking, repair)
+	if len(objects) == 0 {
+		return "", nil, fmt.Errorf("no valid JSON found in input")
+	}
+	last := objects[len(objects)-1]
+	return last.object, last.repairs, nil
+}
+
+// jsonObjects returns the valid top-level JSON objects in s in order, stopping at the first one if first is set.
+// With repair, objects that RepairJSON can fix are returned repaired.
+// Empty objects are skipped, since braces such as those of find -exec {} \; parse as one.
+func jsonObjects(s string, first, repair bool) []candidate {
+	var objects []candidate
+	for start := strings.IndexByte(s, '{'); start != -1; {
+		next := start + 1
+		if end := objectEnd(s, start, repair); end != -1 && !isEmptyObject(s[start:end]) {
+			object, repairs := s[start:end], []string(nil)
+			if repair {
+				object, repairs = RepairJSON(object)
 			}
-			stack = append(stack, ch)
-		case '}':
-			if len(stack) > 0 {
-				stack = stack[:len(stack)-1] // Pop the top '{'
-				if len(stack) == 0 {
-					end = i + 1 // Record JSON end position
-					found = true
+			if json.Valid([]byte(object)) {
+				objects = append(objects, candidate{object: object, repairs: repairs})
+				if first {
 					break
 				}
+				next = end
 			}
 		}
+		idx := strings.IndexByte(s[next:], '{')
+		if idx == -1 {
+			break
+		}
+		start = next + idx
 	}
+	return objects
+}
 
-	if !found || end <= start {
-		return "", fmt.Errorf("no valid JSON found in input")
-	}
-
-	jsonStr := input[start:end]
+// isEmptyObject reports whether object has nothing between its braces
+func isEmptyObject(object string) bool {
+	return strings.TrimSpace(object[1:len(object)-1]) == ""
+}
 
-	// Validate if it's valid JSON
-	var js map[string]interface{}
-	if err := json.Unmarshal([]byte(jsonStr), &js); err != nil {
-		return "", fmt.Errorf("extracted string is not valid JSON: %w", err)
+// objectEnd returns the index just past the object that starts with the brace at s[start],
+// ignoring braces inside JSON strings, or -1 if the object is not closed.
+// When lenient, braces inside single-quoted strings and comments are ignored too.
+func objectEnd(s string, start int, lenient bool) int {
+	depth := 0
+	var quote byte
+	escaped := false
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+			continue
+		}
+		if lenient {
+			if end := commentEnd(s, i); end != -1 {
+				i = end - 1
+				continue
+			}
+		}
+		switch c {
+		case '"':
+			quote = c
+		case '\'':
+			if lenient {
+				quote = c
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
 	}
-
-	return jsonStr, nil
+	return -1
 }
 
 // PrettyPrintJSON formats JSON string with indentation
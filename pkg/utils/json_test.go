@@ -45,6 +45,61 @@ func TestExtractFirstJSON(t *testing.T) {
 			expected: `{"message": "Hello \"world\"!", "emoji": "🚀"}`,
 			wantErr:  false,
 		},
+		{
+			name:     "braces inside strings",
+			input:    `Here: {"success": true, "script": "awk '{print $1}' access.log | sort | uniq -c"} done`,
+			expected: `{"success": true, "script": "awk '{print $1}' access.log | sort | uniq -c"}`,
+		},
+		{
+			name:     "unbalanced braces inside strings",
+			input:    `{"script": "find . -name '*.tmp' -exec rm {} \\;", "note": "a lone } or {"}`,
+			expected: `{"script": "find . -name '*.tmp' -exec rm {} \\;", "note": "a lone } or {"}`,
+		},
+		{
+			name:     "escaped quote before a brace",
+			input:    `{"script": "echo \"}\" > out.txt"}`,
+			expected: `{"script": "echo \"}\" > out.txt"}`,
+		},
+		{
+			name:     "invalid candidate before a valid one",
+			input:    `Use ${HOME} or {placeholder} here: {"success": true, "script": "ls"}`,
+			expected: `{"success": true, "script": "ls"}`,
+		},
+		{
+			name:     "object nested in an invalid candidate",
+			input:    `{ note: {"success": true, "script": "ls"}`,
+			expected: `{"success": true, "script": "ls"}`,
+		},
+		{
+			name:     "fenced json block preferred",
+			input:    "For example {\"script\": \"old\"}, but run this:\n```json\n{\"script\": \"new\"}\n```\n",
+			expected: `{"script": "new"}`,
+		},
+		{
+			name:     "fenced block with invalid JSON is skipped",
+			input:    "```json\n{\"script\": }\n```\nAnswer: {\"script\": \"ls\"}",
+			expected: `{"script": "ls"}`,
+		},
+		{
+			name:     "fenced shell block is not JSON",
+			input:    "```bash\nfind . -exec echo {} \\;\n```\n{\"script\": \"ls\"}",
+			expected: `{"script": "ls"}`,
+		},
+		{
+			name:     "empty object in a command",
+			input:    `Run find . -exec rm {} \; with: {"script": "find . -name '*.tmp' -delete"}`,
+			expected: `{"script": "find . -name '*.tmp' -delete"}`,
+		},
+		{
+			name:     "last object after think block",
+			input:    "<think>Maybe {\"script\": \"rm\"}?</think>\nDraft: {\"script\": \"ls\"}\nFinal: {\"script\": \"ls -la\"}",
+			expected: `{"script": "ls -la"}`,
+		},
+		{
+			name:    "JSON only inside think block",
+			input:   `<think>{"script": "ls"}</think> I cannot answer that.`,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
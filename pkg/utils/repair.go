@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Names of the repairs RepairJSON reports
+const (
+	RepairComments          = "comments"
+	RepairSingleQuotes      = "single quotes"
+	RepairUnescapedNewlines = "unescaped newlines"
+	RepairTrailingCommas    = "trailing commas"
+)
+
+// repairs are the passes of RepairJSON in the order they run. Comments are removed first, so that
+// they cannot hide a trailing comma, and single quotes are replaced before the strings they delimit are escaped.
+var repairs = []struct {
+	name string
+	fix  func(string) (string, bool)
+}{
+	{RepairComments, removeComments},
+	{RepairSingleQuotes, replaceSingleQuotes},
+	{RepairUnescapedNewlines, escapeControlCharacters},
+	{RepairTrailingCommas, removeTrailingCommas},
+}
+
+// RepairJSON fixes the defects small models commonly produce in JSON: comments, single-quoted strings,
+// raw newlines and tabs inside strings, and trailing commas. It returns the repaired text and the names
+// of the repairs that changed it. The result is not guaranteed to be valid JSON.
+func RepairJSON(input string) (string, []string) {
+	var applied []string
+	for _, repair := range repairs {
+		var changed bool
+		if input, changed = repair.fix(input); changed {
+			applied = append(applied, repair.name)
+		}
+	}
+	return input, applied
+}
+
+// removeComments removes // and /* */ comments outside single- and double-quoted strings.
+// An unclosed block comment is left as is.
+func removeComments(s string) (string, bool) {
+	var b strings.Builder
+	changed := false
+	var quote byte
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+			b.WriteByte(c)
+			continue
+		}
+		if end := commentEnd(s, i); end != -1 {
+			changed = true
+			i = end - 1
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), changed
+}
+
+// commentEnd returns the index just past the comment that starts at s[i], or -1 if no comment starts there.
+// The newline ending a line comment is not part of it.
+func commentEnd(s string, i int) int {
+	switch {
+	case strings.HasPrefix(s[i:], "//"):
+		if idx := strings.IndexByte(s[i:], '\n'); idx != -1 {
+			return i + idx
+		}
+		return len(s)
+	case strings.HasPrefix(s[i:], "/*"):
+		if idx := strings.Index(s[i+2:], "*/"); idx != -1 {
+			return i + 2 + idx + 2
+		}
+	}
+	return -1
+}
+
+// replaceSingleQuotes turns single-quoted strings into double-quoted ones, escaping the double quotes they contain.
+// Single quotes inside double-quoted strings, such as those of a shell command, are left alone.
+func replaceSingleQuotes(s string) (string, bool) {
+	var b strings.Builder
+	changed := false
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == 0:
+			if c == '"' || c == '\'' {
+				quote = c
+			}
+			if c == '\'' {
+				c = '"'
+				changed = true
+			}
+		case c == '\\' && i+1 < len(s):
+			i++
+			if quote == '\'' && s[i] == '\'' {
+				// \' needs no escape in a double-quoted string
+				b.WriteByte('\'')
+				continue
+			}
+			b.WriteByte(c)
+			c = s[i]
+		case c == quote:
+			quote = 0
+			c = '"'
+		case c == '"' && quote == '\'':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), changed
+}
+
+// escapeControlCharacters escapes newlines, tabs and other control characters inside double-quoted strings
+func escapeControlCharacters(s string) (string, bool) {
+	var b strings.Builder
+	changed := false
+	inString := false
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !inString {
+			inString = c == '"'
+			b.WriteByte(c)
+			continue
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inString = false
+		case c < 0x20:
+			changed = true
+			switch c {
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				fmt.Fprintf(&b, `\u%04x`, c)
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), changed
+}
+
+// removeTrailingCommas removes commas followed only by whitespace before a closing brace or bracket
+func removeTrailingCommas(s string) (string, bool) {
+	var b strings.Builder
+	changed := false
+	inString := false
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			b.WriteByte(c)
+			continue
+		}
+		if c == ',' {
+			next := strings.TrimLeft(s[i+1:], " \t\r\n")
+			if strings.HasPrefix(next, "}") || strings.HasPrefix(next, "]") {
+				changed = true
+				continue
+			}
+		}
+		inString = c == '"'
+		b.WriteByte(c)
+	}
+	return b.String(), changed
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		wantRepairs []string
+	}{
+		{
+			name:     "valid JSON is unchanged",
+			input:    `{"success": true, "script": "ls -la"}`,
+			expected: `{"success": true, "script": "ls -la"}`,
+		},
+		{
+			name:        "trailing commas",
+			input:       `{"success": true, "script": "ls", "steps": [{"script": "pwd"},],}`,
+			expected:    `{"success": true, "script": "ls", "steps": [{"script": "pwd"}]}`,
+			wantRepairs: []string{RepairTrailingCommas},
+		},
+		{
+			name:        "trailing comma before newline",
+			input:       "{\n  \"success\": true,\n  \"script\": \"ls\",\n}",
+			expected:    "{\n  \"success\": true,\n  \"script\": \"ls\"\n}",
+			wantRepairs: []string{RepairTrailingCommas},
+		},
+		{
+			name:     "comma inside a string is kept",
+			input:    `{"script": "echo a,}"}`,
+			expected: `{"script": "echo a,}"}`,
+		},
+		{
+			name:        "single quotes",
+			input:       `{'success': true, 'script': 'echo "hi"'}`,
+			expected:    `{"success": true, "script": "echo \"hi\""}`,
+			wantRepairs: []string{RepairSingleQuotes},
+		},
+		{
+			name:        "escaped single quote",
+			input:       `{'script': 'echo it\'s done'}`,
+			expected:    `{"script": "echo it's done"}`,
+			wantRepairs: []string{RepairSingleQuotes},
+		},
+		{
+			name:     "single quotes inside a double-quoted string are kept",
+			input:    `{"script": "find . -name '*.tmp' -delete"}`,
+			expected: `{"script": "find . -name '*.tmp' -delete"}`,
+		},
+		{
+			name:        "unescaped newlines",
+			input:       "{\"success\": true, \"script\": \"cd /tmp\n\tls -la\"}",
+			expected:    `{"success": true, "script": "cd /tmp\n\tls -la"}`,
+			wantRepairs: []string{RepairUnescapedNewlines},
+		},
+		{
+			name:     "newlines between fields are kept",
+			input:    "{\n\"script\": \"ls\"\n}",
+			expected: "{\n\"script\": \"ls\"\n}",
+		},
+		{
+			name:        "line and block comments",
+			input:       "{\n  // list the files\n  \"script\": \"ls\", /* quiet */ \"success\": true\n}",
+			expected:    "{\n  \n  \"script\": \"ls\",  \"success\": true\n}",
+			wantRepairs: []string{RepairComments},
+		},
+		{
+			name:     "comment markers inside a string are kept",
+			input:    `{"script": "curl https://example.com/*.txt"}`,
+			expected: `{"script": "curl https://example.com/*.txt"}`,
+		},
+		{
+			name:        "all defects",
+			input:       "{\n  'success': true, // ready\n  'script': 'cd /tmp\nls',\n}",
+			expected:    "{\n  \"success\": true, \n  \"script\": \"cd /tmp\\nls\"\n}",
+			wantRepairs: []string{RepairComments, RepairSingleQuotes, RepairUnescapedNewlines, RepairTrailingCommas},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, repairs := RepairJSON(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("expected %q but got %q", tt.expected, result)
+			}
+			if !reflect.DeepEqual(repairs, tt.wantRepairs) {
+				t.Errorf("expected repairs %q but got %q", tt.wantRepairs, repairs)
+			}
+			if !json.Valid([]byte(result)) {
+				t.Errorf("repaired JSON is not valid: %q", result)
+			}
+		})
+	}
+}
+
+func TestExtractRepairedJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		wantRepairs []string
+		wantErr     bool
+	}{
+		{
+			name:     "valid JSON needs no repair",
+			input:    `Here: {"success": true, "script": "ls"}`,
+			expected: `{"success": true, "script": "ls"}`,
+		},
+		{
+			name:        "near-miss object in text",
+			input:       `Sure! {'success': true, 'script': 'du -sh .',} Let me know.`,
+			expected:    `{"success": true, "script": "du -sh ."}`,
+			wantRepairs: []string{RepairSingleQuotes, RepairTrailingCommas},
+		},
+		{
+			name:        "brace in a single-quoted string",
+			input:       `{'success': true, 'script': 'awk "{print $1}" log'}`,
+			expected:    `{"success": true, "script": "awk \"{print $1}\" log"}`,
+			wantRepairs: []string{RepairSingleQuotes},
+		},
+		{
+			name:        "apostrophe in a comment",
+			input:       "{\"script\": \"ls\" // don't recurse\n}",
+			expected:    "{\"script\": \"ls\" \n}",
+			wantRepairs: []string{RepairComments},
+		},
+		{
+			name:        "fenced block",
+			input:       "```json\n{\"success\": true, \"script\": \"cd /tmp\nls\",}\n```",
+			expected:    `{"success": true, "script": "cd /tmp\nls"}`,
+			wantRepairs: []string{RepairUnescapedNewlines, RepairTrailingCommas},
+		},
+		{
+			name:        "last object after think block",
+			input:       "<think>{'script': 'rm'}</think>\n{'script': 'ls',}",
+			expected:    `{"script": "ls"}`,
+			wantRepairs: []string{RepairSingleQuotes, RepairTrailingCommas},
+		},
+		{
+			name:    "beyond repair",
+			input:   `{success: true, script: ls}`,
+			wantErr: true,
+		},
+		{
+			name:    "no JSON",
+			input:   `You can run du -sh . to see it.`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, repairs, err := ExtractRepairedJSON(tt.input)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				if result != "" {
+					t.Errorf("expected empty result but got %q", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q but got %q", tt.expected, result)
+			}
+			if !reflect.DeepEqual(repairs, tt.wantRepairs) {
+				t.Errorf("expected repairs %q but got %q", tt.wantRepairs, repairs)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Package validate checks JSON returned by a model against the fields the application expects,
+// coercing common mistakes such as "true" written as a string and reporting every problem precisely.
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Type is the JSON type expected for a field
+type Type int
+
+// JSON types a field can have
+const (
+	String Type = iota
+	Boolean
+	Number
+	Array
+	Object
+)
+
+// Field describes a value in the expected JSON
+type Field struct {
+	Name     string
+	Type     Type
+	Required bool
+	// Elem describes the elements of an Array
+	Elem *Field
+	// Fields describes the properties of an Object; properties not listed are kept as they are
+	Fields []Field
+	// OneOf lists properties of an Object of which at least one is required
+	OneOf []string
+}
+
+// Error lists every problem found in a JSON value
+type Error struct {
+	Problems []string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return "invalid response: " + strings.Join(e.Problems, "; ")
+}
+
+// Normalize checks data against schema and returns it with coercions applied, ready to be unmarshalled.
+// Strings "true" and "false" are accepted for booleans, and numeric strings for numbers.
+// If data is not valid JSON or does not match the schema, the returned error is an *Error.
+func Normalize(data []byte, schema *Field) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, &Error{Problems: []string{fmt.Sprintf("not valid JSON: %v", err)}}
+	}
+
+	var problems []string
+	value = check(value, schema, "", &problems)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal normalized JSON: %w", err)
+	}
+	return normalized, nil
+}
+
+// check validates value against field, appending problems for path, and returns the coerced value
+func check(value interface{}, field *Field, path string, problems *[]string) interface{} {
+	where := location(path)
+
+	switch field.Type {
+	case String:
+		if _, ok := value.(string); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s must be a string, got %s", where, typeName(value)))
+		}
+	case Boolean:
+		switch v := value.(type) {
+		case bool:
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true":
+				return true
+			case "false":
+				return false
+			}
+			*problems = append(*problems, fmt.Sprintf("%s must be true or false, got %q", where, v))
+		default:
+			*problems = append(*problems, fmt.Sprintf("%s must be a boolean, got %s", where, typeName(value)))
+		}
+	case Number:
+		switch v := value.(type) {
+		case json.Number:
+		case string:
+			n := json.Number(strings.TrimSpace(v))
+			if _, err := n.Float64(); err == nil {
+				return n
+			}
+			*problems = append(*problems, fmt.Sprintf("%s must be a number, got %q", where, v))
+		default:
+			*problems = append(*problems, fmt.Sprintf("%s must be a number, got %s", where, typeName(value)))
+		}
+	case Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s must be an array, got %s", where, typeName(value)))
+			break
+		}
+		if field.Elem != nil {
+			for i, item := range items {
+				items[i] = check(item, field.Elem, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case Object:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s must be an object, got %s", where, typeName(value)))
+			break
+		}
+		checkObject(object, field, path, problems)
+	}
+	return value
+}
+
+// checkObject validates the properties of object against field
+func checkObject(object map[string]interface{}, field *Field, path string, problems *[]string) {
+	for i := range field.Fields {
+		property := &field.Fields[i]
+		propertyPath := property.Name
+		if path != "" {
+			propertyPath = path + "." + property.Name
+		}
+
+		value, ok := object[property.Name]
+		if !ok || value == nil {
+			if property.Required {
+				*problems = append(*problems, fmt.Sprintf("missing required field %q", propertyPath))
+			}
+			continue
+		}
+		object[property.Name] = check(value, property, propertyPath, problems)
+	}
+
+	if len(field.OneOf) == 0 {
+		return
+	}
+	for _, name := range field.OneOf {
+		if value, ok := object[name]; ok && value != nil {
+			return
+		}
+	}
+	*problems = append(*problems, fmt.Sprintf("%s must have one of the fields %s", location(path), quoteAll(field.OneOf)))
+}
+
+// location names the value at path in error messages
+func location(path string) string {
+	if path == "" {
+		return "response"
+	}
+	return path
+}
+
+// typeName returns the JSON type name of a decoded value
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// quoteAll quotes and joins names for an error message
+func quoteAll(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// JSONSchema returns the field as a JSON Schema, for backends that constrain their output to a schema.
+// OneOf is not expressed in the schema and is only enforced by Normalize.
+func (f *Field) JSONSchema() map[string]interface{} {
+	switch f.Type {
+	case Boolean:
+		return map[string]interface{}{"type": "boolean"}
+	case Number:
+		return map[string]interface{}{"type": "number"}
+	case Array:
+		schema := map[string]interface{}{"type": "array"}
+		if f.Elem != nil {
+			schema["items"] = f.Elem.JSONSchema()
+		}
+		return schema
+	case Object:
+		properties := make(map[string]interface{}, len(f.Fields))
+		required := []string{}
+		for i := range f.Fields {
+			properties[f.Fields[i].Name] = f.Fields[i].JSONSchema()
+			if f.Fields[i].Required {
+				required = append(required, f.Fields[i].Name)
+			}
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
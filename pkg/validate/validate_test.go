@@ -0,0 +1,148 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testSchema = &Field{
+	Type:  Object,
+	OneOf: []string{"script", "steps"},
+	Fields: []Field{
+		{Name: "success", Type: Boolean, Required: true},
+		{Name: "multipleLines", Type: Boolean},
+		{Name: "script", Type: String},
+		{Name: "count", Type: Number},
+		{Name: "steps", Type: Array, Elem: &Field{
+			Type: Object,
+			Fields: []Field{
+				{Name: "description", Type: String, Required: true},
+				{Name: "script", Type: String, Required: true},
+			},
+		}},
+		{Name: "tags", Type: Array, Elem: &Field{Type: String}},
+	},
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		problems []string
+	}{
+		{
+			name:     "valid",
+			input:    `{"success": true, "multipleLines": false, "script": "ls"}`,
+			expected: `{"multipleLines":false,"script":"ls","success":true}`,
+		},
+		{
+			name:     "string booleans and numbers",
+			input:    `{"success": "true", "multipleLines": " False ", "script": "ls", "count": "3"}`,
+			expected: `{"count":3,"multipleLines":false,"script":"ls","success":true}`,
+		},
+		{
+			name:     "unknown fields are kept",
+			input:    `{"success": true, "script": "ls", "note": {"a": 1}}`,
+			expected: `{"note":{"a":1},"script":"ls","success":true}`,
+		},
+		{
+			name:     "null optional field",
+			input:    `{"success": true, "script": "ls", "multipleLines": null}`,
+			expected: `{"multipleLines":null,"script":"ls","success":true}`,
+		},
+		{
+			name:     "missing required field",
+			input:    `{"script": "ls"}`,
+			problems: []string{`missing required field "success"`},
+		},
+		{
+			name:     "one of",
+			input:    `{"success": true}`,
+			problems: []string{`response must have one of the fields "script", "steps"`},
+		},
+		{
+			name:  "wrong types",
+			input: `{"success": "yes", "script": 42, "count": "many", "tags": "a"}`,
+			problems: []string{
+				`success must be true or false, got "yes"`,
+				`script must be a string, got number`,
+				`count must be a number, got "many"`,
+				`tags must be an array, got string`,
+			},
+		},
+		{
+			name:  "nested paths",
+			input: `{"success": true, "steps": [{"description": "a", "script": "ls"}, {"script": ["ls"]}], "tags": ["x", 1]}`,
+			problems: []string{
+				`missing required field "steps[1].description"`,
+				`steps[1].script must be a string, got array`,
+				`tags[1] must be a string, got number`,
+			},
+		},
+		{
+			name:     "not an object",
+			input:    `[1, 2]`,
+			problems: []string{`response must be an object, got array`},
+		},
+		{
+			name:     "malformed",
+			input:    `{"success": true,}`,
+			problems: []string{`not valid JSON: invalid character '}' looking for beginning of object key string`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize([]byte(tt.input), testSchema)
+			if tt.problems != nil {
+				var invalid *Error
+				if !errors.As(err, &invalid) {
+					t.Fatalf("expected *Error but got %v", err)
+				}
+				if !reflect.DeepEqual(invalid.Problems, tt.problems) {
+					t.Errorf("expected problems %q but got %q", tt.problems, invalid.Problems)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("expected %s but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := &Error{Problems: []string{"a", "b"}}
+	if err.Error() != "invalid response: a; b" {
+		t.Errorf("unexpected message: %s", err.Error())
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := &Field{
+		Type: Object,
+		Fields: []Field{
+			{Name: "success", Type: Boolean, Required: true},
+			{Name: "count", Type: Number},
+			{Name: "tags", Type: Array, Elem: &Field{Type: String}},
+		},
+	}
+
+	expected := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"success": map[string]interface{}{"type": "boolean"},
+			"count":   map[string]interface{}{"type": "number"},
+			"tags":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required": []string{"success"},
+	}
+	if got := schema.JSONSchema(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}